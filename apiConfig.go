@@ -1,28 +1,48 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/contentfilter"
 	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/entities"
+	"david-galdamez/chirp/internal/events"
+	"david-galdamez/chirp/internal/mailer"
+	"david-galdamez/chirp/internal/pagination"
+	"david-galdamez/chirp/internal/storage"
+	"david-galdamez/chirp/internal/unfurl"
 	"david-galdamez/chirp/models"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
+	"net/mail"
 	"os"
-	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	queries        *database.Queries
+	filter         *contentfilter.Filter
+	hub            *events.Hub
+	storage        storage.Storage
+	unfurler       *unfurl.Fetcher
+	previewQueue   chan string
+	restoreWindow  time.Duration
+	mailer         mailer.Mailer
+	retention      time.Duration
 	secretKey      string
 	polkaKey       string
+	adminKey       string
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -62,8 +82,55 @@ func (cfg *apiConfig) resetMetric(w http.ResponseWriter, r *http.Request) {
 }
 
 type ChirpRequest struct {
-	Body   string    `json:"body"`
-	UserId uuid.UUID `json:"user_id"`
+	Body          string      `json:"body"`
+	ParentChirpId *uuid.UUID  `json:"parent_chirp_id"`
+	MediaIds      []uuid.UUID `json:"media_ids"`
+	PublishAt     *time.Time  `json:"publish_at"`
+	Visibility    string      `json:"visibility"`
+}
+
+// cleanChirpBody enforces the length limit and masks banned words.
+func (cfg *apiConfig) cleanChirpBody(body string) (string, error) {
+	if len(body) > 140 {
+		return "", errors.New("Chirp is too long")
+	}
+
+	return cfg.filter.Clean(body), nil
+}
+
+// insertChirp stores a new chirp with its tags, links, mentions and media
+// and notifies the author of the parent chirp, if any. Only the author's own
+// uploads that are not on another chirp are attached; the number attached
+// is returned so callers can decide whether a shortfall is an error.
+func insertChirp(ctx context.Context, q *database.Queries, params database.CreateChirpParams, parentUserId uuid.NullUUID, mediaIds []uuid.UUID) (database.Chirp, int64, error) {
+	chirp, err := q.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, 0, err
+	}
+
+	if err := saveChirpEntities(ctx, q, chirp); err != nil {
+		return database.Chirp{}, 0, err
+	}
+
+	attached := int64(0)
+	if len(mediaIds) > 0 {
+		attached, err = q.AttachMediaFiles(ctx, database.AttachMediaFilesParams{
+			ChirpID:  chirp.ID,
+			MediaIds: mediaIds,
+			UserID:   chirp.UserID,
+		})
+		if err != nil {
+			return database.Chirp{}, 0, err
+		}
+	}
+
+	if parentUserId.Valid {
+		if err := notify(ctx, q, parentUserId.UUID, chirp.UserID, notificationReply, uuid.NullUUID{UUID: chirp.ID, Valid: true}); err != nil {
+			return database.Chirp{}, 0, err
+		}
+	}
+
+	return chirp, attached, nil
 }
 
 func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
@@ -75,7 +142,7 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = auth.ValidateJWT(token, cfg.secretKey)
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
@@ -83,6 +150,10 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !cfg.requireVerifiedEmail(w, r, userId) {
+		return
+	}
+
 	request := ChirpRequest{}
 
 	decoder := json.NewDecoder(r.Body)
@@ -97,8 +168,9 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(request.Body) > 140 {
-		errRes := ErrorResponse{Error: "Chirp is too long"}
+	body, err := cfg.cleanChirpBody(request.Body)
+	if err != nil {
+		errRes := ErrorResponse{Error: err.Error()}
 		data, _ := json.Marshal(errRes)
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -106,15 +178,84 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, word := range invalid_words {
-		request.Body = strings.ReplaceAll(request.Body, strings.ToLower(word), "****")
-		request.Body = strings.ReplaceAll(request.Body, strings.ToUpper(word), "****")
+	if len(request.MediaIds) > maxChirpMedia {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "too many media files"}`))
+		return
+	}
+
+	if request.PublishAt != nil && !request.PublishAt.After(time.Now()) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "publish_at must be in the future"}`))
+		return
 	}
 
-	chirpDb, err := cfg.queries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   request.Body,
-		UserID: request.UserId,
-	})
+	if request.Visibility == "" {
+		request.Visibility = visibilityPublic
+	}
+
+	if !chirpVisibilities[request.Visibility] {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "visibility must be public, followers or private"}`))
+		return
+	}
+
+	parentChirpId := uuid.NullUUID{}
+	var parentChirp database.Chirp
+	if request.ParentChirpId != nil {
+		parentChirp, err = cfg.getVisibleChirp(r.Context(), *request.ParentChirpId, uuid.NullUUID{UUID: userId, Valid: true})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "parent chirp not found"}`))
+				return
+			}
+
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+
+		parentChirpId = uuid.NullUUID{UUID: *request.ParentChirpId, Valid: true}
+	}
+
+	if request.PublishAt != nil {
+		cfg.scheduleChirp(w, r, database.CreateDraftParams{
+			UserID:        userId,
+			Body:          body,
+			ParentChirpID: parentChirpId,
+			MediaIds:      request.MediaIds,
+			PublishAt:     sql.NullTime{Time: request.PublishAt.UTC(), Valid: true},
+			Visibility:    request.Visibility,
+		})
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating chirp"`))
+		return
+	}
+	defer tx.Rollback()
+
+	parentUserId := uuid.NullUUID{}
+	if parentChirpId.Valid {
+		parentUserId = uuid.NullUUID{UUID: parentChirp.UserID, Valid: true}
+	}
+
+	chirpDb, attached, err := insertChirp(r.Context(), cfg.queries.WithTx(tx), database.CreateChirpParams{
+		Body:          body,
+		UserID:        userId,
+		ParentChirpID: parentChirpId,
+		Visibility:    request.Visibility,
+	}, parentUserId, request.MediaIds)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -122,15 +263,33 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chirp := models.Chirp{
-		ID:        chirpDb.ID,
-		CreatedAt: chirpDb.CreatedAt,
-		UpdatedAt: chirpDb.UpdatedAt,
-		Body:      chirpDb.Body,
-		UserId:    chirpDb.UserID,
+	if attached != int64(len(request.MediaIds)) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid media ids"}`))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating chirp"`))
+		return
+	}
+
+	cfg.queueLinkPreviews(chirpDb.Body)
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
 	}
 
-	data, err := json.Marshal(chirp)
+	cfg.broadcastChirp(r.Context(), chirps[0])
+
+	data, err := json.Marshal(chirps[0])
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -144,9 +303,10 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 }
 
 type UserRequest struct {
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-	IsChirpyRed bool   `json:"is_chirpy_red"`
+	Email       string  `json:"email"`
+	Password    string  `json:"password"`
+	Handle      *string `json:"handle"`
+	IsChirpyRed bool    `json:"is_chirpy_red"`
 }
 
 func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
@@ -161,6 +321,25 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !validEmail(request.Email) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid email"}`))
+		return
+	}
+
+	handle := sql.NullString{}
+	if request.Handle != nil {
+		if !entities.ValidHandle(*request.Handle) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "handle must be 1-30 letters, digits or underscores"}`))
+			return
+		}
+
+		handle = sql.NullString{String: strings.ToLower(*request.Handle), Valid: true}
+	}
+
 	hashedPassword, err := auth.HashPassword(request.Password)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
@@ -169,10 +348,38 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userDatabase, err := cfg.queries.CreateUser(r.Context(), database.CreateUserParams{
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating user"`))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+
+	userDatabase, err := qtx.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          request.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "email or handle already taken"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating user"`))
+		return
+	}
+
+	verificationToken, err := issueVerificationToken(r.Context(), qtx, userDatabase)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -180,14 +387,17 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user := models.User{
-		ID:          userDatabase.ID,
-		CreatedAt:   userDatabase.CreatedAt,
-		UpdatedAt:   userDatabase.UpdatedAt,
-		Email:       userDatabase.Email,
-		IsChirpyRed: userDatabase.IsChirpyRed,
+	if err := tx.Commit(); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating user"`))
+		return
 	}
 
+	cfg.sendVerificationEmail(r.Context(), userDatabase.Email, verificationToken)
+
+	user := newUser(userDatabase)
+
 	jsonResponse, err := json.Marshal(user)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
@@ -201,6 +411,31 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonResponse)
 }
 
+// newUser converts a user row into the account view returned to the user
+// themselves, which unlike models.Profile includes the email.
+func newUser(user database.User) models.User {
+	newUser := models.User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		Bio:           user.Bio,
+		IsChirpyRed:   user.IsChirpyRed,
+	}
+
+	if user.Handle.Valid {
+		newUser.Handle = &user.Handle.String
+	}
+
+	if user.AvatarMediaID.Valid {
+		newUser.AvatarMediaId = &user.AvatarMediaID.UUID
+	}
+
+	return newUser
+}
+
 type UserLogin struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -218,22 +453,683 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userDB, err := cfg.queries.GetUser(r.Context(), request.Email)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			w.Header().Add("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(`"error": "Chirp not found"`))
-			return
-		}
-
+	userDB, err := cfg.queries.GetUser(r.Context(), request.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`"error": "Chirp not found"`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`"error" : "Incorrect email or password"`))
+		return
+	}
+
+	isPassword, err := auth.CheckPasswordHash(request.Password, userDB.HashedPassword)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	if !isPassword {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`"error" : "Incorrect email or password"`))
+		return
+	}
+
+	token, err := auth.MakeJWT(userDB.ID, cfg.secretKey, time.Hour*1)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error generating auth token"`))
+		return
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error generating refresh token"`))
+		return
+	}
+
+	refreshTokenDb, err := cfg.queries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userDB.ID,
+		ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Error creating refresh token"`))
+		return
+	}
+
+	user := newUser(userDB)
+	user.Token = &token
+	user.RefreshToken = &refreshTokenDb.Token
+
+	jsonRes, err := json.Marshal(user)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func newChirp(chirp database.Chirp) models.Chirp {
+	newChirp := models.Chirp{
+		ID:         chirp.ID,
+		CreatedAt:  chirp.CreatedAt,
+		UpdatedAt:  chirp.UpdatedAt,
+		Body:       chirp.Body,
+		UserId:     chirp.UserID,
+		Visibility: chirp.Visibility,
+	}
+
+	if chirp.ParentChirpID.Valid {
+		newChirp.ParentChirpId = &chirp.ParentChirpID.UUID
+	}
+
+	if chirp.RepostOfID.Valid {
+		newChirp.RepostOfId = &chirp.RepostOfID.UUID
+	}
+
+	return newChirp
+}
+
+// viewerId returns the user behind an optional bearer token. Requests
+// without an Authorization header are anonymous and yield a null id.
+func (cfg *apiConfig) viewerId(r *http.Request) (uuid.NullUUID, error) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: userId, Valid: true}, nil
+}
+
+// toChirpModels converts database chirps into API models, filling in the
+// counters that live in other tables. When viewer is set, liked_by_me is
+// filled in for that user.
+func (cfg *apiConfig) toChirpModels(ctx context.Context, chirpsDB []database.Chirp, viewer uuid.NullUUID) ([]models.Chirp, error) {
+	chirps := []models.Chirp{}
+	if len(chirpsDB) == 0 {
+		return chirps, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirpsDB))
+	for _, chirp := range chirpsDB {
+		ids = append(ids, chirp.ID)
+	}
+
+	replyCounts, err := cfg.queries.GetReplyCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	replies := map[uuid.UUID]int64{}
+	for _, count := range replyCounts {
+		replies[count.ChirpID] = count.ReplyCount
+	}
+
+	likeCounts, err := cfg.queries.GetLikeCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	likes := map[uuid.UUID]int64{}
+	for _, count := range likeCounts {
+		likes[count.ChirpID] = count.LikeCount
+	}
+
+	liked := map[uuid.UUID]bool{}
+	if viewer.Valid {
+		likedIds, err := cfg.queries.GetLikedChirpIds(ctx, database.GetLikedChirpIdsParams{
+			UserID:   viewer.UUID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range likedIds {
+			liked[id] = true
+		}
+	}
+
+	tagRows, err := cfg.queries.GetChirpTags(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[uuid.UUID][]string{}
+	for _, row := range tagRows {
+		tags[row.ChirpID] = append(tags[row.ChirpID], row.Name)
+	}
+
+	mentionRows, err := cfg.queries.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := map[uuid.UUID][]models.Mention{}
+	for _, row := range mentionRows {
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], models.Mention{
+			UserId: row.UserID,
+			Handle: row.Handle,
+		})
+	}
+
+	mediaFiles, err := cfg.queries.GetChirpMediaFiles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	chirpMedia := map[uuid.UUID][]models.Media{}
+	for _, file := range mediaFiles {
+		chirpMedia[file.ChirpID.UUID] = append(chirpMedia[file.ChirpID.UUID], cfg.newMedia(file))
+	}
+
+	previewRows, err := cfg.queries.GetChirpLinkPreviews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := map[uuid.UUID]*models.LinkPreview{}
+	for _, row := range previewRows {
+		previews[row.ChirpID] = &models.LinkPreview{
+			URL:         row.Url,
+			Title:       row.Title,
+			Description: row.Description,
+			ImageURL:    row.ImageUrl,
+			SiteName:    row.SiteName,
+		}
+	}
+
+	for _, chirp := range chirpsDB {
+		newChirp := newChirp(chirp)
+		newChirp.ReplyCount = replies[chirp.ID]
+		newChirp.Tags = tags[chirp.ID]
+		if newChirp.Tags == nil {
+			newChirp.Tags = []string{}
+		}
+		newChirp.Mentions = mentions[chirp.ID]
+		if newChirp.Mentions == nil {
+			newChirp.Mentions = []models.Mention{}
+		}
+		newChirp.Media = chirpMedia[chirp.ID]
+		if newChirp.Media == nil {
+			newChirp.Media = []models.Media{}
+		}
+		newChirp.Preview = previews[chirp.ID]
+		newChirp.LikeCount = likes[chirp.ID]
+		if viewer.Valid {
+			likedByMe := liked[chirp.ID]
+			newChirp.LikedByMe = &likedByMe
+		}
+
+		chirps = append(chirps, newChirp)
+	}
+
+	return chirps, nil
+}
+
+// chirpSortKeys lists the columns GET /api/chirps can be ordered by.
+var chirpSortKeys = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"like_count": true,
+}
+
+func chirpSortValue(chirp database.Chirp, likeCount int64, sortKey string) pagination.SortKey {
+	switch sortKey {
+	case "like_count":
+		return pagination.CountKey(likeCount)
+	case "updated_at":
+		return pagination.TimeKey(chirp.UpdatedAt)
+	default:
+		return pagination.TimeKey(chirp.CreatedAt)
+	}
+}
+
+func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
+
+	var chirpsDB []database.Chirp
+	var likeCounts []int64
+
+	authorId := r.URL.Query().Get("author_id")
+
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "asc" && sortBy != "desc" {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "sort must be asc or desc"}`))
+		return
+	}
+
+	sortKey := r.URL.Query().Get("sort_by")
+	if sortKey == "" {
+		sortKey = "created_at"
+	}
+	if !chirpSortKeys[sortKey] {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "unknown sort_by value"}`))
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid limit"}`))
+		return
+	}
+
+	// the cursor records the sort it was made for, so it can't be reused
+	// with another one
+	sortDesc := sortBy == "desc"
+	cursorSort := sortKey + ":asc"
+	if sortDesc {
+		cursorSort = sortKey + ":desc"
+	}
+
+	cursorKey, cursorId, err := pagination.ParseSortedCursor(r.URL.Query().Get("cursor"), cursorSort)
+	if err != nil {
+		if errors.Is(err, pagination.ErrSortMismatch) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "cursor does not match sort and sort_by"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid cursor"}`))
+		return
+	}
+
+	if cursorId.Valid && cursorKey.Count.Valid != (sortKey == "like_count") {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid cursor"}`))
+		return
+	}
+
+	// one extra row tells us whether there is a next page
+	pageLimit := int32(limit + 1)
+
+	if authorId == "" {
+
+		rows, err := cfg.queries.GetChirpsPage(r.Context(), database.GetChirpsPageParams{
+			ViewerID:    viewer,
+			CursorKey:   cursorKey.Time,
+			CursorCount: cursorKey.Count,
+			SortDesc:    sortDesc,
+			SortKey:     sortKey,
+			CursorID:    cursorId,
+			PageLimit:   pageLimit,
+		})
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`"error" : "Internal server error"`))
+			return
+		}
+
+		for _, row := range rows {
+			chirpsDB = append(chirpsDB, row.Chirp)
+			likeCounts = append(likeCounts, row.LikeCount)
+		}
+	} else {
+
+		parsedAuthorId, err := uuid.Parse(authorId)
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`"error" : "Internal server error"`))
+			return
+		}
+
+		rows, err := cfg.queries.GetChirpsByIdPage(r.Context(), database.GetChirpsByIdPageParams{
+			UserID:      parsedAuthorId,
+			ViewerID:    viewer,
+			CursorKey:   cursorKey.Time,
+			CursorCount: cursorKey.Count,
+			SortDesc:    sortDesc,
+			SortKey:     sortKey,
+			CursorID:    cursorId,
+			PageLimit:   pageLimit,
+		})
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`"error" : "Internal server error"`))
+			return
+		}
+
+		for _, row := range rows {
+			chirpsDB = append(chirpsDB, row.Chirp)
+			likeCounts = append(likeCounts, row.LikeCount)
+		}
+	}
+
+	page := models.ChirpsPage{}
+	if len(chirpsDB) > limit {
+		chirpsDB = chirpsDB[:limit]
+		last := chirpsDB[len(chirpsDB)-1]
+		nextCursor := pagination.EncodeSortedCursor(chirpSortValue(last, likeCounts[len(chirpsDB)-1], sortKey), last.ID, cursorSort)
+		page.NextCursor = &nextCursor
+	}
+
+	page.Chirps, err = cfg.toChirpModels(r.Context(), chirpsDB, viewer)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(page)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
+	parsedId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	chirpDb, err := cfg.getVisibleChirp(r.Context(), parsedId, viewer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`"error": "Chirp not found"`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, viewer)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(chirps[0])
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	parsedId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(r.Context(), parsedId, viewer); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "chirp not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	// replies the viewer cannot see are left out together with their own
+	// replies; a hidden root hides the whole thread
+	rows, err := cfg.queries.GetChirpThread(r.Context(), database.GetChirpThreadParams{
+		ID:       parsedId,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if len(rows) == 0 {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "chirp not found"}`))
+		return
+	}
+
+	chirpsDB := []database.Chirp{}
+	for _, row := range rows {
+		chirpsDB = append(chirpsDB, database.Chirp{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Body:          row.Body,
+			UserID:        row.UserID,
+			ParentChirpID: row.ParentChirpID,
+			RepostOfID:    row.RepostOfID,
+			Visibility:    row.Visibility,
+		})
+	}
+
+	chirps, err := cfg.toChirpModels(r.Context(), chirpsDB, viewer)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	// rows come ordered by depth, so every parent is seen before its replies
+	nodes := map[uuid.UUID]*models.ChirpThread{}
+	root := &models.ChirpThread{Chirp: chirps[0], Replies: []*models.ChirpThread{}}
+	nodes[root.ID] = root
+	for _, chirp := range chirps[1:] {
+		node := &models.ChirpThread{Chirp: chirp, Replies: []*models.ChirpThread{}}
+		nodes[chirp.ID] = node
+
+		parent := nodes[*chirp.ParentChirpId]
+		parent.Replies = append(parent.Replies, node)
+	}
+
+	jsonRes, err := json.Marshal(root)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+// highlightSnippet turns a SearchChirps snippet into HTML. The chirp text is
+// escaped before the match markers become <mark> tags, so user content can
+// never inject markup.
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>").Replace(html.EscapeString(snippet))
+}
+
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "search query not provided"}`))
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid limit"}`))
+		return
+	}
+
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	rows, err := cfg.queries.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:     query,
+		ViewerID:  viewer,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	chirpsDB := make([]database.Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirpsDB = append(chirpsDB, database.Chirp{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			Body:          row.Body,
+			UserID:        row.UserID,
+			ParentChirpID: row.ParentChirpID,
+			RepostOfID:    row.RepostOfID,
+			Visibility:    row.Visibility,
+		})
+	}
+
+	// search hits carry the same counters and attachments as other listings
+	chirps, err := cfg.toChirpModels(r.Context(), chirpsDB, viewer)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	results := make([]models.ChirpSearchResult, 0, len(rows))
+	for i, row := range rows {
+		results = append(results, models.ChirpSearchResult{
+			Chirp:   chirps[i],
+			Rank:    row.Rank,
+			Snippet: highlightSnippet(row.Snippet),
+		})
+	}
+
+	jsonRes, err := json.Marshal(results)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
+	refreshToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	refreshTokenDB, err := cfg.queries.GetRefreshToken(r.Context(), refreshToken)
+	if err != nil || time.Now().After(refreshTokenDB.ExpiresAt) {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`"error" : "Incorrect email or password"`))
+		w.Write([]byte(`"error" : "Not authorized"`))
 		return
 	}
 
-	isPassword, err := auth.CheckPasswordHash(request.Password, userDB.HashedPassword)
+	newToken, err := auth.MakeJWT(refreshTokenDB.UserID, cfg.secretKey, time.Hour*1)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -241,166 +1137,198 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !isPassword {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf(`"token" : "%v"`, newToken)))
+}
+
+func (cfg *apiConfig) revokeToken(w http.ResponseWriter, r *http.Request) {
+	refreshToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`"error" : "Incorrect email or password"`))
+		w.Write([]byte(`"error" : "unauthorized"`))
 		return
 	}
 
-	token, err := auth.MakeJWT(userDB.ID, cfg.secretKey, time.Hour*1)
+	_, err = cfg.queries.RevokeToken(r.Context(), refreshToken)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Error generating auth token"`))
+		w.Write([]byte(`"error" : "Internal server error"`))
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	refreshToken, err := auth.MakeRefreshToken()
+// ChangeUserRequest holds the account fields to change; omitted fields are
+// left as they are. Changing the password requires the current one.
+type ChangeUserRequest struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+// validEmail reports whether email is a single bare address such as
+// "user@example.com", without a display name.
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// updateUser changes the email and/or password of the authenticated user.
+// A password change revokes every refresh token of the user, so other
+// sessions have to log in again. It serves both PATCH and the older PUT
+// route, which existing clients still call.
+func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Error generating refresh token"`))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`"error" : "unauthorized"`))
 		return
 	}
 
-	refreshTokenDb, err := cfg.queries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    userDB.ID,
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
-	})
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Error creating refresh token"`))
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
 		return
 	}
 
-	user := models.User{
-		ID:           userDB.ID,
-		CreatedAt:    userDB.CreatedAt,
-		UpdatedAt:    userDB.UpdatedAt,
-		Email:        userDB.Email,
-		Token:        &token,
-		RefreshToken: &refreshTokenDb.Token,
-		IsChirpyRed:  userDB.IsChirpyRed,
-	}
+	userRequest := ChangeUserRequest{}
 
-	jsonRes, err := json.Marshal(user)
-	if err != nil {
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&userRequest); err != nil {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`"error" : "Bad request"`))
 		return
 	}
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonRes)
-}
+	params := database.UpdateUserParams{ID: userId}
 
-func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
+	if userRequest.Email != nil {
+		if !validEmail(*userRequest.Email) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid email"}`))
+			return
+		}
 
-	var chirpsDB []database.Chirp
+		params.Email = sql.NullString{String: *userRequest.Email, Valid: true}
+	}
 
-	authorId := r.URL.Query().Get("author_id")
-	sortBy := r.URL.Query().Get("sort")
-	if authorId == "" {
+	if userRequest.Password != nil {
+		if *userRequest.Password == "" {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "password cannot be empty"}`))
+			return
+		}
 
-		var err error
-		chirpsDB, err = cfg.queries.GetChirps(r.Context())
+		userDB, err := cfg.queries.GetUserById(r.Context(), userId)
 		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": "unauthorized"}`))
+				return
+			}
+
 			w.Header().Add("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`"error" : "Internal server error"`))
+			w.Write([]byte(`{"error": "Internal server error"}`))
 			return
 		}
-	} else {
-
-		var err error
 
-		parsedAuthorId, err := uuid.Parse(authorId)
+		isPassword, err := auth.CheckPasswordHash(userRequest.CurrentPassword, userDB.HashedPassword)
 		if err != nil {
 			w.Header().Add("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`"error" : "Internal server error"`))
+			w.Write([]byte(`{"error": "Internal server error"}`))
 			return
 		}
 
-		chirpsDB, err = cfg.queries.GetChirpsById(r.Context(), parsedAuthorId)
+		if !isPassword {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "current password is incorrect"}`))
+			return
+		}
+
+		hashedPassword, err := auth.HashPassword(*userRequest.Password)
 		if err != nil {
 			w.Header().Add("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(`"error" : "Internal server error"`))
 			return
 		}
-	}
-
-	chirps := []models.Chirp{}
-	for _, chirp := range chirpsDB {
-		newChirp := models.Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserId:    chirp.UserID,
-		}
-
-		chirps = append(chirps, newChirp)
-	}
 
-	if sortBy == "desc" {
-		sort.Slice(chirps, func(i, j int) bool { return chirps[i].CreatedAt.After(chirps[j].CreatedAt) })
+		params.HashedPassword = sql.NullString{String: hashedPassword, Valid: true}
 	}
 
-	jsonRes, err := json.Marshal(chirps)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
+	defer tx.Rollback()
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(jsonRes)
-}
+	qtx := cfg.queries.WithTx(tx)
+
+	userDB, err := qtx.UpdateUser(r.Context(), params)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "email already taken"}`))
+			return
+		}
 
-func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
-	chirpId := r.PathValue("chirpID")
-	if chirpId == "" {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`"error": "Id is not valid"`))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`"error" : "Internal server error"`))
 		return
 	}
 
-	parsedId := uuid.MustParse(chirpId)
+	if params.HashedPassword.Valid {
+		if err := qtx.RevokeUserRefreshTokens(r.Context(), userId); err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+	}
 
-	chirpDb, err := cfg.queries.GetChirp(r.Context(), parsedId)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+	// a changed email has to be verified again
+	verificationToken := ""
+	if params.Email.Valid && !userDB.EmailVerified {
+		verificationToken, err = issueVerificationToken(r.Context(), qtx, userDB)
+		if err != nil {
 			w.Header().Add("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(`"error": "Chirp not found"`))
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
 			return
 		}
+	}
 
+	if err := tx.Commit(); err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	chirp := models.Chirp{
-		ID:        chirpDb.ID,
-		CreatedAt: chirpDb.CreatedAt,
-		UpdatedAt: chirpDb.UpdatedAt,
-		Body:      chirpDb.Body,
-		UserId:    chirpDb.UserID,
+	if verificationToken != "" {
+		cfg.sendVerificationEmail(r.Context(), userDB.Email, verificationToken)
 	}
 
-	jsonRes, err := json.Marshal(chirp)
+	jsonRes, err := json.Marshal(newUser(userDB))
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -413,8 +1341,12 @@ func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonRes)
 }
 
-func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
-	refreshToken, err := auth.GetBearerToken(r.Header)
+type UpdateChirpRequest struct {
+	Body string `json:"body"`
+}
+
+func (cfg *apiConfig) updateChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
@@ -422,119 +1354,203 @@ func (cfg *apiConfig) refreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshTokenDB, err := cfg.queries.GetRefreshToken(r.Context(), refreshToken)
-	if err != nil || time.Now().After(refreshTokenDB.ExpiresAt) {
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`"error" : "Not authorized"`))
+		w.Write([]byte(`{"error": "unauthorized"}`))
 		return
 	}
 
-	newToken, err := auth.MakeJWT(refreshTokenDB.UserID, cfg.secretKey, time.Hour*1)
+	parsedId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	request := UpdateChirpRequest{}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Bad request"}`))
+		return
+	}
+
+	body, err := cfg.cleanChirpBody(request.Body)
+	if err != nil {
+		errRes := ErrorResponse{Error: err.Error()}
+		data, _ := json.Marshal(errRes)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(data)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
+	defer tx.Rollback()
 
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(fmt.Sprintf(`"token" : "%v"`, newToken)))
-}
+	qtx := cfg.queries.WithTx(tx)
 
-func (cfg *apiConfig) revokeToken(w http.ResponseWriter, r *http.Request) {
-	refreshToken, err := auth.GetBearerToken(r.Header)
+	chirpDb, err := qtx.GetChirpForUpdate(r.Context(), parsedId)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "chirp not found"}`))
+			return
+		}
+
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`"error" : "unauthorized"`))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	_, err = cfg.queries.RevokeToken(r.Context(), refreshToken)
+	if chirpDb.UserID != userId {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": "chirp does not belongs to user"}`))
+		return
+	}
+
+	// the body is what tells a plain rechirp from a quote, so an edit must
+	// not turn one into the other
+	if chirpDb.RepostOfID.Valid && chirpDb.Body == "" {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "plain rechirps can't be edited"}`))
+		return
+	}
+
+	if chirpDb.RepostOfID.Valid && body == "" {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "quote chirps need a body"}`))
+		return
+	}
+
+	if _, err := qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID: chirpDb.ID,
+		Body:    chirpDb.Body,
+	}); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error saving chirp revision"}`))
+		return
+	}
+
+	chirpDb, err = qtx.UpdateChirp(r.Context(), database.UpdateChirpParams{
+		Body: body,
+		ID:   chirpDb.ID,
+	})
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Error updating chirp"}`))
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
-}
 
-type ChangeUserRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-}
+	if err := saveChirpEntities(r.Context(), qtx, chirpDb); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error updating chirp"}`))
+		return
+	}
 
-type UserResponse struct {
-	Id          uuid.UUID `json:"id"`
-	Email       string    `json:"email"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
-}
+	if err := tx.Commit(); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error updating chirp"}`))
+		return
+	}
 
-func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+	cfg.queueLinkPreviews(chirpDb.Body)
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, uuid.NullUUID{UUID: userId, Valid: true})
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`"error" : "unauthorized"`))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	jsonRes, err := json.Marshal(chirps[0])
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`{"error": "unauthorized"}`))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	userRequest := ChangeUserRequest{}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&userRequest); err != nil {
+func (cfg *apiConfig) getChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	parsedId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`"error" : "Bad request"`))
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(userRequest.Password)
+	viewer, err := cfg.viewerId(r)
 	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	if _, err := cfg.getVisibleChirp(r.Context(), parsedId, viewer); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "chirp not found"}`))
+			return
+		}
+
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	userDB, err := cfg.queries.UpdateUser(r.Context(), database.UpdateUserParams{
-		Email:          userRequest.Email,
-		HashedPassword: hashedPassword,
-		ID:             userId,
-	})
+	revisionsDb, err := cfg.queries.GetChirpRevisions(r.Context(), parsedId)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
-	userRes := UserResponse{
-		Id:          userDB.ID,
-		Email:       userDB.Email,
-		CreatedAt:   userDB.CreatedAt,
-		UpdatedAt:   userDB.UpdatedAt,
-		IsChirpyRed: userDB.IsChirpyRed,
+	revisions := []models.ChirpRevision{}
+	for _, revision := range revisionsDb {
+		revisions = append(revisions, models.ChirpRevision{
+			ID:        revision.ID,
+			ChirpID:   revision.ChirpID,
+			Body:      revision.Body,
+			CreatedAt: revision.CreatedAt,
+		})
 	}
 
-	jsonRes, err := json.Marshal(userRes)
+	jsonRes, err := json.Marshal(revisions)
 	if err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`"error" : "Internal server error"`))
+		w.Write([]byte(`{"error": "Internal server error"}`))
 		return
 	}
 
@@ -597,13 +1613,24 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cfg.queries.DeleteChirp(r.Context(), chirp.ID); err != nil {
+	// the row and its media stay until the purge job so the chirp can still
+	// be restored
+	if err := cfg.queries.SoftDeleteChirp(r.Context(), chirp.ID); err != nil {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`"error" : "Internal server error"`))
 		return
 	}
 
+	// only public chirps were ever announced
+	if chirp.Visibility == visibilityPublic {
+		cfg.publishEvent(r.Context(), events.Event{
+			Type:    events.ChirpDeleted,
+			ChirpID: chirp.ID,
+			UserID:  chirp.UserID,
+		})
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -666,5 +1693,10 @@ func (cfg *apiConfig) chirpyRed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cfg.publishEvent(r.Context(), events.Event{
+		Type:   events.UserUpgraded,
+		UserID: parsedId,
+	})
+
 	w.WriteHeader(http.StatusNoContent)
 }
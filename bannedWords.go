@@ -0,0 +1,106 @@
+package main
+
+import (
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/contentfilter"
+	"encoding/json"
+	"net/http"
+)
+
+type BannedWordRequest struct {
+	Word string `json:"word"`
+}
+
+// isAdmin checks the ApiKey authorization header against ADMIN_KEY. Admin
+// endpoints stay closed when no key is configured.
+func (cfg *apiConfig) isAdmin(r *http.Request) bool {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return false
+	}
+
+	return cfg.adminKey != "" && apiKey == cfg.adminKey
+}
+
+func (cfg *apiConfig) getBannedWords(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(cfg.filter.Words())
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) addBannedWord(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	request := BannedWordRequest{}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Bad request"}`))
+		return
+	}
+
+	word := contentfilter.Normalize(request.Word)
+	if !contentfilter.IsWord(word) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "banned word must be a single word"}`))
+		return
+	}
+
+	if err := cfg.queries.CreateBannedWord(r.Context(), word); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	cfg.filter.Add(word)
+	cfg.publishBannedWordsChanged(r.Context())
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) removeBannedWord(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	word := contentfilter.Normalize(r.PathValue("word"))
+
+	if err := cfg.queries.DeleteBannedWord(r.Context(), word); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	cfg.filter.Remove(word)
+	cfg.publishBannedWordsChanged(r.Context())
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"database/sql"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DraftRequest struct {
+	Body          string      `json:"body"`
+	ParentChirpId *uuid.UUID  `json:"parent_chirp_id"`
+	MediaIds      []uuid.UUID `json:"media_ids"`
+	PublishAt     *time.Time  `json:"publish_at"`
+	Visibility    string      `json:"visibility"`
+}
+
+// draftParams validates a draft request, writing the error response when it
+// is not valid. Scheduled drafts are checked and cleaned like a chirp right
+// away, author included, since the scheduler publishes them unattended;
+// other drafts are kept as written until they are published.
+func (cfg *apiConfig) draftParams(w http.ResponseWriter, r *http.Request, userId uuid.UUID, request DraftRequest) (database.CreateDraftParams, bool) {
+	params := database.CreateDraftParams{
+		UserID:     userId,
+		Body:       request.Body,
+		MediaIds:   request.MediaIds,
+		Visibility: request.Visibility,
+	}
+
+	if params.Visibility == "" {
+		params.Visibility = visibilityPublic
+	}
+
+	if !chirpVisibilities[params.Visibility] {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "visibility must be public, followers or private"}`))
+		return params, false
+	}
+
+	if len(request.MediaIds) > maxChirpMedia {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "too many media files"}`))
+		return params, false
+	}
+
+	if request.PublishAt != nil {
+		if !cfg.requireVerifiedEmail(w, r, userId) {
+			return params, false
+		}
+
+		if !request.PublishAt.After(time.Now()) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "publish_at must be in the future"}`))
+			return params, false
+		}
+
+		body, err := cfg.cleanChirpBody(request.Body)
+		if err != nil {
+			data, _ := json.Marshal(ErrorResponse{Error: err.Error()})
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(data)
+			return params, false
+		}
+
+		params.Body = body
+		params.PublishAt = sql.NullTime{Time: request.PublishAt.UTC(), Valid: true}
+	}
+
+	if request.ParentChirpId != nil {
+		if _, err := cfg.getVisibleChirp(r.Context(), *request.ParentChirpId, uuid.NullUUID{UUID: userId, Valid: true}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "parent chirp not found"}`))
+				return params, false
+			}
+
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return params, false
+		}
+
+		params.ParentChirpID = uuid.NullUUID{UUID: *request.ParentChirpId, Valid: true}
+	}
+
+	if len(request.MediaIds) > 0 {
+		attachable, err := cfg.queries.CountAttachableMediaFiles(r.Context(), database.CountAttachableMediaFilesParams{
+			MediaIds: request.MediaIds,
+			UserID:   userId,
+		})
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return params, false
+		}
+
+		if attachable != int64(len(request.MediaIds)) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid media ids"}`))
+			return params, false
+		}
+	}
+
+	return params, true
+}
+
+func (cfg *apiConfig) createDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	request := DraftRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "malformed request body"}`))
+		return
+	}
+
+	params, ok := cfg.draftParams(w, r, userId, request)
+	if !ok {
+		return
+	}
+
+	draft, err := cfg.queries.CreateDraft(r.Context(), params)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error creating draft"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(newDraft(draft))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getDrafts(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftsDb, err := cfg.queries.GetDrafts(r.Context(), userId)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	drafts := []models.Draft{}
+	for _, draft := range draftsDb {
+		drafts = append(drafts, newDraft(draft))
+	}
+
+	jsonRes, err := json.Marshal(drafts)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftId, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from draft id"}`))
+		return
+	}
+
+	draft, err := cfg.queries.GetDraft(r.Context(), database.GetDraftParams{
+		ID:     draftId,
+		UserID: userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "draft not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(newDraft(draft))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) updateDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftId, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from draft id"}`))
+		return
+	}
+
+	request := DraftRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "malformed request body"}`))
+		return
+	}
+
+	params, ok := cfg.draftParams(w, r, userId, request)
+	if !ok {
+		return
+	}
+
+	draft, err := cfg.queries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:            draftId,
+		UserID:        userId,
+		Body:          params.Body,
+		ParentChirpID: params.ParentChirpID,
+		MediaIds:      params.MediaIds,
+		PublishAt:     params.PublishAt,
+		Visibility:    params.Visibility,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "draft not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error updating draft"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(newDraft(draft))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) deleteDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftId, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from draft id"}`))
+		return
+	}
+
+	deleted, err := cfg.queries.DeleteUserDraft(r.Context(), database.DeleteUserDraftParams{
+		ID:     draftId,
+		UserID: userId,
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if deleted == 0 {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "draft not found"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishDraft turns a draft into a chirp right away, scheduled or not. The
+// draft is locked, published and deleted in one transaction, so it cannot
+// also be published by the scheduler or a second request.
+func (cfg *apiConfig) publishDraft(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftId, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from draft id"}`))
+		return
+	}
+
+	if !cfg.requireVerifiedEmail(w, r, userId) {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error publishing draft"}`))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+
+	draft, err := qtx.GetDraftForUpdate(r.Context(), database.GetDraftForUpdateParams{
+		ID:     draftId,
+		UserID: userId,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "draft not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	body, err := cfg.cleanChirpBody(draft.Body)
+	if err != nil {
+		data, _ := json.Marshal(ErrorResponse{Error: err.Error()})
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(data)
+		return
+	}
+
+	// a parent deleted since the draft was saved turns it into a top-level
+	// chirp
+	parentUserId := uuid.NullUUID{}
+	if draft.ParentChirpID.Valid {
+		parent, err := qtx.GetChirp(r.Context(), draft.ParentChirpID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+
+		if err == nil {
+			parentUserId = uuid.NullUUID{UUID: parent.UserID, Valid: true}
+		} else {
+			draft.ParentChirpID = uuid.NullUUID{}
+		}
+	}
+
+	chirpDb, attached, err := insertChirp(r.Context(), qtx, database.CreateChirpParams{
+		Body:          body,
+		UserID:        userId,
+		ParentChirpID: draft.ParentChirpID,
+		Visibility:    draft.Visibility,
+	}, parentUserId, draft.MediaIds)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error publishing draft"}`))
+		return
+	}
+
+	if attached != int64(len(draft.MediaIds)) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid media ids"}`))
+		return
+	}
+
+	if err := qtx.DeleteDraft(r.Context(), draft.ID); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error publishing draft"}`))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error publishing draft"}`))
+		return
+	}
+
+	cfg.queueLinkPreviews(chirpDb.Body)
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	cfg.broadcastChirp(r.Context(), chirps[0])
+
+	jsonRes, err := json.Marshal(chirps[0])
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonRes)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"david-galdamez/chirp/internal/events"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"log"
+)
+
+// publishEvent sends an event to every instance through Postgres NOTIFY.
+// The local hub receives it back through events.ListenPostgres like any
+// other instance. If the database is unreachable the event is only
+// delivered locally.
+func (cfg *apiConfig) publishEvent(ctx context.Context, event events.Event) {
+	id, err := cfg.queries.NextEventId(ctx)
+	if err != nil {
+		log.Printf("Error publishing event: %v", err)
+		cfg.hub.Publish(event)
+		return
+	}
+	event.ID = uint64(id)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error publishing event: %v", err)
+		cfg.hub.Publish(event)
+		return
+	}
+
+	if err := cfg.queries.NotifyEvent(ctx, string(payload)); err != nil {
+		log.Printf("Error publishing event: %v", err)
+		cfg.hub.Publish(event)
+	}
+}
+
+// publishBannedWordsChanged asks every instance to reload its banned word
+// list. It bypasses the event ids since subscribers never see it.
+func (cfg *apiConfig) publishBannedWordsChanged(ctx context.Context) {
+	payload, err := json.Marshal(events.Event{Type: events.BannedWordsChanged})
+	if err != nil {
+		log.Printf("Error publishing banned words change: %v", err)
+		return
+	}
+
+	if err := cfg.queries.NotifyEvent(ctx, string(payload)); err != nil {
+		log.Printf("Error publishing banned words change: %v", err)
+	}
+}
+
+// reloadBannedWords rebuilds the filter from the banned_words table.
+func (cfg *apiConfig) reloadBannedWords() {
+	words, err := cfg.queries.GetBannedWords(context.Background())
+	if err != nil {
+		log.Printf("Error reloading banned words: %v", err)
+		return
+	}
+
+	cfg.filter.Replace(words)
+}
+
+// broadcastChirp announces a new public chirp. Subscribers are not the
+// author, so the author's liked_by_me is dropped.
+func (cfg *apiConfig) broadcastChirp(ctx context.Context, chirp models.Chirp) {
+	if chirp.Visibility != visibilityPublic {
+		return
+	}
+
+	chirp.LikedByMe = nil
+	cfg.publishEvent(ctx, events.Event{
+		Type:    events.ChirpCreated,
+		ChirpID: chirp.ID,
+		UserID:  chirp.UserId,
+		Chirp:   &chirp,
+	})
+}
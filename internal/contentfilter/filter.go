@@ -0,0 +1,185 @@
+package contentfilter
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const mask = "****"
+
+// Filter masks banned words in chirp bodies. Words are matched as whole
+// words after normalization, so "Kerfuffle" and "kérfuffle" are caught while
+// "kerfuffles" is left alone. It is safe for concurrent use.
+type Filter struct {
+	mu    sync.RWMutex
+	words map[string]struct{}
+}
+
+func New(words []string) *Filter {
+	f := &Filter{words: map[string]struct{}{}}
+	for _, word := range words {
+		f.Add(word)
+	}
+
+	return f
+}
+
+// Normalize folds a word to the form used for matching: compatibility
+// decomposed, stripped of combining marks and lowercased.
+func Normalize(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// IsWord reports whether a normalized word is a single word the filter
+// can match, i.e. it is not empty and has no spaces or punctuation.
+func IsWord(word string) bool {
+	if word == "" {
+		return false
+	}
+
+	for _, r := range word {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func (f *Filter) Add(word string) {
+	word = Normalize(word)
+	if !IsWord(word) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.words[word] = struct{}{}
+}
+
+// Replace swaps the whole word list, e.g. after another instance changed it.
+func (f *Filter) Replace(words []string) {
+	replacement := map[string]struct{}{}
+	for _, word := range words {
+		word = Normalize(word)
+		if IsWord(word) {
+			replacement[word] = struct{}{}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.words = replacement
+}
+
+func (f *Filter) Remove(word string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.words, Normalize(word))
+}
+
+func (f *Filter) Words() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	words := make([]string, 0, len(f.words))
+	for word := range f.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
+}
+
+// Clean replaces every banned word in text with a mask, leaving the rest of
+// the text untouched.
+func (f *Filter) Clean(text string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.words) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	start := -1
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			i += size
+			continue
+		}
+
+		if start >= 0 {
+			b.WriteString(f.maskWord(text[start:i]))
+			start = -1
+		}
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+
+	if start >= 0 {
+		b.WriteString(f.maskWord(text[start:]))
+	}
+
+	return b.String()
+}
+
+func (f *Filter) maskWord(word string) string {
+	if _, ok := f.words[Normalize(word)]; ok {
+		return mask
+	}
+
+	return word
+}
+
+// LoadFile reads a word list with one word per line. Blank lines and lines
+// starting with # are ignored.
+func LoadFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	words := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return words, nil
+}
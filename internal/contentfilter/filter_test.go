@@ -0,0 +1,128 @@
+package contentfilter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	filter := New([]string{"kerfuffle", "sharbert", "Fornax"})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no banned words", "I had something interesting for breakfast", "I had something interesting for breakfast"},
+		{"lowercase", "this is a kerfuffle opinion", "this is a **** opinion"},
+		{"mixed case", "This is a Kerfuffle opinion", "This is a **** opinion"},
+		{"uppercase", "KERFUFFLE", "****"},
+		{"word list entry with uppercase", "fornax", "****"},
+		{"longer word", "kerfuffles are fine", "kerfuffles are fine"},
+		{"prefix", "akerfuffle", "akerfuffle"},
+		{"trailing punctuation", "what a kerfuffle!", "what a ****!"},
+		{"surrounding punctuation", "(sharbert)", "(****)"},
+		{"several words", "kerfuffle, sharbert and fornax", "****, **** and ****"},
+		{"accented", "what a kérfuffle", "what a ****"},
+		{"decomposed accent", "what a ke\u0301rfuffle", "what a ****"},
+		{"fullwidth", "ｋｅｒｆｕｆｆｌｅ", "****"},
+		{"non-ascii neighbours kept", "café kerfuffle naïve", "café **** naïve"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.Clean(tt.text); got != tt.want {
+				t.Errorf("Clean(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"Kerfuffle", "kerfuffle"},
+		{"kérfuffle", "kerfuffle"},
+		{"ｋｅｒｆｕｆｆｌｅ", "kerfuffle"},
+		{"ﬁne", "fine"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.word); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestIsWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want bool
+	}{
+		{"kerfuffle", true},
+		{"abc123", true},
+		{"", false},
+		{"two words", false},
+		{"dash-ed", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsWord(tt.word); got != tt.want {
+			t.Errorf("IsWord(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestAddRemove(t *testing.T) {
+	filter := New(nil)
+
+	filter.Add("Sharbert")
+	filter.Add("not a word")
+	if got := filter.Clean("sharbert"); got != "****" {
+		t.Errorf("Clean after Add = %q, want %q", got, "****")
+	}
+
+	filter.Remove("SHARBERT")
+	if got := filter.Clean("sharbert"); got != "sharbert" {
+		t.Errorf("Clean after Remove = %q, want %q", got, "sharbert")
+	}
+
+	if got := filter.Words(); len(got) != 0 {
+		t.Errorf("Words() = %v, want none", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# banned\nkerfuffle\n\n  sharbert  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"kerfuffle", "sharbert"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("LoadFile() = %v, want %v", words, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	filter := New([]string{"kerfuffle"})
+
+	filter.Replace([]string{"Sharbert", "not a word"})
+
+	if want := []string{"sharbert"}; !reflect.DeepEqual(filter.Words(), want) {
+		t.Errorf("Words() = %v, want %v", filter.Words(), want)
+	}
+
+	if got := filter.Clean("kerfuffle sharbert"); got != "kerfuffle ****" {
+		t.Errorf("Clean after Replace = %q, want %q", got, "kerfuffle ****")
+	}
+}
@@ -0,0 +1,75 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: banned_words.sql
+
+package database
+
+import (
+	"context"
+)
+
+const createBannedWord = `-- name: CreateBannedWord :exec
+INSERT INTO banned_words (word)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) CreateBannedWord(ctx context.Context, word string) error {
+	_, err := q.db.ExecContext(ctx, createBannedWord, word)
+	return err
+}
+
+const deleteBannedWord = `-- name: DeleteBannedWord :exec
+DELETE FROM banned_words WHERE word = $1
+`
+
+func (q *Queries) DeleteBannedWord(ctx context.Context, word string) error {
+	_, err := q.db.ExecContext(ctx, deleteBannedWord, word)
+	return err
+}
+
+const getBannedWords = `-- name: GetBannedWords :many
+SELECT word FROM banned_words ORDER BY word ASC
+`
+
+func (q *Queries) GetBannedWords(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getBannedWords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, err
+		}
+		items = append(items, word)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const seedBannedWord = `-- name: SeedBannedWord :exec
+WITH seeded AS (
+    INSERT INTO banned_word_seeds (word)
+    VALUES ($1)
+    ON CONFLICT DO NOTHING
+    RETURNING word
+)
+INSERT INTO banned_words (word)
+SELECT word FROM seeded
+ON CONFLICT DO NOTHING
+`
+
+// adds the word unless it was seeded before
+func (q *Queries) SeedBannedWord(ctx context.Context, word string) error {
+	_, err := q.db.ExecContext(ctx, seedBannedWord, word)
+	return err
+}
@@ -0,0 +1,113 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: bookmarks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const bookmarkChirp = `-- name: BookmarkChirp :exec
+INSERT INTO bookmarks (user_id, chirp_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type BookmarkChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) BookmarkChirp(ctx context.Context, arg BookmarkChirpParams) error {
+	_, err := q.db.ExecContext(ctx, bookmarkChirp, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const getBookmarks = `-- name: GetBookmarks :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, chirps.deleted_at, bookmarks.created_at AS bookmarked_at FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = $1
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = $1
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = $1 AND follows.followee_id = chirps.user_id)))
+    AND ($2::timestamp IS NULL
+        OR (bookmarks.created_at, bookmarks.chirp_id) < ($2::timestamp, $3::uuid))
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT $4
+`
+
+type GetBookmarksParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+type GetBookmarksRow struct {
+	Chirp        Chirp
+	BookmarkedAt time.Time
+}
+
+// bookmarks of deleted chirps, or of chirps the user can no longer see, are
+// skipped rather than removed
+func (q *Queries) GetBookmarks(ctx context.Context, arg GetBookmarksParams) ([]GetBookmarksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookmarks,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookmarksRow
+	for rows.Next() {
+		var i GetBookmarksRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.RepostOfID,
+			&i.Chirp.Visibility,
+			&i.Chirp.DeletedAt,
+			&i.BookmarkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unbookmarkChirp = `-- name: UnbookmarkChirp :exec
+DELETE FROM bookmarks WHERE user_id = $1 AND chirp_id = $2
+`
+
+type UnbookmarkChirpParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) UnbookmarkChirp(ctx context.Context, arg UnbookmarkChirpParams) error {
+	_, err := q.db.ExecContext(ctx, unbookmarkChirp, arg.UserID, arg.ChirpID)
+	return err
+}
@@ -7,29 +7,44 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility)
 VALUES(
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RepostOfID    uuid.NullUUID
+	Visibility    string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentChirpID,
+		arg.RepostOfID,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,25 +52,41 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1
+const getChirp = `-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
-	return err
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -63,16 +94,216 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+const getChirpThread = `-- name: GetChirpThread :many
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_chirp_id FROM chirps WHERE chirps.id = $1 AND deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.parent_chirp_id FROM chirps c JOIN ancestors a ON c.id = a.parent_chirp_id
+    WHERE c.deleted_at IS NULL
+), thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, 0 AS depth
+    FROM chirps JOIN ancestors a ON chirps.id = a.id
+    WHERE NOT EXISTS (SELECT 1 FROM ancestors p WHERE p.id = a.parent_chirp_id)
+        AND (chirps.visibility = 'public'
+            OR chirps.user_id = $2::uuid
+            OR (chirps.visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM follows
+                WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id)))
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, t.depth + 1
+    FROM chirps JOIN thread t ON chirps.parent_chirp_id = t.id
+    WHERE chirps.deleted_at IS NULL
+        AND (chirps.visibility = 'public'
+            OR chirps.user_id = $2::uuid
+            OR (chirps.visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM follows
+                WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id)))
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.repost_of_id, thread.visibility, thread.depth::int AS depth
+FROM thread
+ORDER BY depth ASC, created_at ASC
+`
+
+type GetChirpThreadParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+type GetChirpThreadRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RepostOfID    uuid.NullUUID
+	Visibility    string
+	Depth         int32
+}
+
+// the thread is rooted at the oldest ancestor that is not deleted
+func (q *Queries) GetChirpThread(ctx context.Context, arg GetChirpThreadParams) ([]GetChirpThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpThread, arg.ID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpThreadRow
+	for rows.Next() {
+		var i GetChirpThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.RepostOfID,
+			&i.Visibility,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIdPage = `-- name: GetChirpsByIdPage :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, chirps.deleted_at, like_counts.like_count FROM chirps
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS like_count FROM likes WHERE likes.chirp_id = chirps.id
+) like_counts
+WHERE chirps.user_id = $1
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = $2::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id)))
+    AND ($3::uuid IS NULL
+        OR ($4::text = 'like_count' AND $5::boolean AND (like_counts.like_count, chirps.id) < ($6::bigint, $3::uuid))
+        OR ($4::text = 'like_count' AND NOT $5::boolean AND (like_counts.like_count, chirps.id) > ($6::bigint, $3::uuid))
+        OR ($4::text <> 'like_count' AND $5::boolean AND (CASE WHEN $4::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) < ($7::timestamp, $3::uuid))
+        OR ($4::text <> 'like_count' AND NOT $5::boolean AND (CASE WHEN $4::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) > ($7::timestamp, $3::uuid)))
+ORDER BY
+    CASE WHEN $5::boolean AND $4::text = 'like_count' THEN like_counts.like_count END DESC,
+    CASE WHEN $5::boolean AND $4::text <> 'like_count' THEN CASE WHEN $4::text = 'updated_at' THEN updated_at ELSE created_at END END DESC,
+    CASE WHEN $5::boolean THEN chirps.id END DESC,
+    CASE WHEN $4::text = 'like_count' THEN like_counts.like_count END ASC,
+    CASE WHEN $4::text <> 'like_count' THEN CASE WHEN $4::text = 'updated_at' THEN updated_at ELSE created_at END END ASC,
+    chirps.id ASC
+LIMIT $8
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsByIdPageParams struct {
+	UserID      uuid.UUID
+	ViewerID    uuid.NullUUID
+	CursorID    uuid.NullUUID
+	SortKey     string
+	SortDesc    bool
+	CursorCount sql.NullInt64
+	CursorKey   sql.NullTime
+	PageLimit   int32
+}
+
+type GetChirpsByIdPageRow struct {
+	Chirp     Chirp
+	LikeCount int64
+}
+
+// like_count is the only numeric sort key; the timestamp keys share the
+// other CASE so each keyset comparison stays on a single type.
+func (q *Queries) GetChirpsByIdPage(ctx context.Context, arg GetChirpsByIdPageParams) ([]GetChirpsByIdPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIdPage,
+		arg.UserID,
+		arg.ViewerID,
+		arg.CursorID,
+		arg.SortKey,
+		arg.SortDesc,
+		arg.CursorCount,
+		arg.CursorKey,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsByIdPageRow
+	for rows.Next() {
+		var i GetChirpsByIdPageRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.RepostOfID,
+			&i.Chirp.Visibility,
+			&i.Chirp.DeletedAt,
+			&i.LikeCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByTag = `-- name: GetChirpsByTag :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, chirps.deleted_at FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE tags.name = $1
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = $2::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id)))
+    AND ($3::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $5
+`
+
+type GetChirpsByTagParams struct {
+	Tag             string
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsByTag(ctx context.Context, arg GetChirpsByTagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByTag,
+		arg.Tag,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +317,10 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.RepostOfID,
+			&i.Visibility,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -100,12 +335,200 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsById = `-- name: GetChirpsById :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+const getChirpsPage = `-- name: GetChirpsPage :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, chirps.deleted_at, like_counts.like_count FROM chirps
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS like_count FROM likes WHERE likes.chirp_id = chirps.id
+) like_counts
+WHERE chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = $1::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = $1::uuid AND follows.followee_id = chirps.user_id)))
+    AND ($2::uuid IS NULL
+        OR ($3::text = 'like_count' AND $4::boolean AND (like_counts.like_count, chirps.id) < ($5::bigint, $2::uuid))
+        OR ($3::text = 'like_count' AND NOT $4::boolean AND (like_counts.like_count, chirps.id) > ($5::bigint, $2::uuid))
+        OR ($3::text <> 'like_count' AND $4::boolean AND (CASE WHEN $3::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) < ($6::timestamp, $2::uuid))
+        OR ($3::text <> 'like_count' AND NOT $4::boolean AND (CASE WHEN $3::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) > ($6::timestamp, $2::uuid)))
+ORDER BY
+    CASE WHEN $4::boolean AND $3::text = 'like_count' THEN like_counts.like_count END DESC,
+    CASE WHEN $4::boolean AND $3::text <> 'like_count' THEN CASE WHEN $3::text = 'updated_at' THEN updated_at ELSE created_at END END DESC,
+    CASE WHEN $4::boolean THEN chirps.id END DESC,
+    CASE WHEN $3::text = 'like_count' THEN like_counts.like_count END ASC,
+    CASE WHEN $3::text <> 'like_count' THEN CASE WHEN $3::text = 'updated_at' THEN updated_at ELSE created_at END END ASC,
+    chirps.id ASC
+LIMIT $7
 `
 
-func (q *Queries) GetChirpsById(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsById, userID)
+type GetChirpsPageParams struct {
+	ViewerID    uuid.NullUUID
+	CursorID    uuid.NullUUID
+	SortKey     string
+	SortDesc    bool
+	CursorCount sql.NullInt64
+	CursorKey   sql.NullTime
+	PageLimit   int32
+}
+
+type GetChirpsPageRow struct {
+	Chirp     Chirp
+	LikeCount int64
+}
+
+// like_count is the only numeric sort key; the timestamp keys share the
+// other CASE so each keyset comparison stays on a single type.
+func (q *Queries) GetChirpsPage(ctx context.Context, arg GetChirpsPageParams) ([]GetChirpsPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPage,
+		arg.ViewerID,
+		arg.CursorID,
+		arg.SortKey,
+		arg.SortDesc,
+		arg.CursorCount,
+		arg.CursorKey,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsPageRow
+	for rows.Next() {
+		var i GetChirpsPageRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.ParentChirpID,
+			&i.Chirp.RepostOfID,
+			&i.Chirp.Visibility,
+			&i.Chirp.DeletedAt,
+			&i.LikeCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirp = `-- name: GetDeletedChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getPlainRechirp = `-- name: GetPlainRechirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at FROM chirps WHERE user_id = $1 AND repost_of_id = $2 AND body = '' AND deleted_at IS NULL
+`
+
+type GetPlainRechirpParams struct {
+	UserID     uuid.UUID
+	RepostOfID uuid.NullUUID
+}
+
+func (q *Queries) GetPlainRechirp(ctx context.Context, arg GetPlainRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getPlainRechirp, arg.UserID, arg.RepostOfID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getReplyCounts = `-- name: GetReplyCounts :many
+SELECT parent_chirp_id::uuid AS chirp_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE parent_chirp_id = ANY($1::uuid[])
+    AND deleted_at IS NULL
+GROUP BY parent_chirp_id
+`
+
+type GetReplyCountsRow struct {
+	ChirpID    uuid.UUID
+	ReplyCount int64
+}
+
+func (q *Queries) GetReplyCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetReplyCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReplyCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReplyCountsRow
+	for rows.Next() {
+		var i GetReplyCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.ReplyCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, chirps.deleted_at FROM chirps
+JOIN follows ON chirps.user_id = follows.followee_id
+WHERE follows.follower_id = $1
+    AND chirps.deleted_at IS NULL
+    AND chirps.visibility <> 'private'
+    AND ($2::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +542,151 @@ func (q *Queries) GetChirpsById(ctx context.Context, userID uuid.UUID) ([]Chirp,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentChirpID,
+			&i.RepostOfID,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :exec
+DELETE FROM chirps
+WHERE deleted_at < $1::timestamp
+    OR (body = '' AND repost_of_id IN (
+        SELECT id FROM chirps
+        WHERE deleted_at < $1::timestamp
+    ))
+`
+
+// Plain rechirps go with the chirp they repost; quotes only lose the link.
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, cutoff time.Time) error {
+	_, err := q.db.ExecContext(ctx, purgeDeletedChirps, cutoff)
+	return err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = $1
+    AND deleted_at > $2::timestamp
+RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at
+`
+
+type RestoreChirpParams struct {
+	ID     uuid.UUID
+	Cutoff time.Time
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.Cutoff)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const restorePlainRechirps = `-- name: RestorePlainRechirps :exec
+UPDATE chirps SET deleted_at = NULL
+WHERE repost_of_id = $1::uuid AND body = '' AND deleted_at = $2::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps live
+        WHERE live.user_id = chirps.user_id
+            AND live.repost_of_id = chirps.repost_of_id
+            AND live.body = ''
+            AND live.deleted_at IS NULL
+    )
+`
+
+type RestorePlainRechirpsParams struct {
+	ChirpID   uuid.UUID
+	DeletedAt time.Time
+}
+
+// A user who rechirped again in the meantime keeps the newer rechirp and
+// the old one stays deleted.
+func (q *Queries) RestorePlainRechirps(ctx context.Context, arg RestorePlainRechirpsParams) error {
+	_, err := q.db.ExecContext(ctx, restorePlainRechirps, arg.ChirpID, arg.DeletedAt)
+	return err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility,
+    ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1))::real AS rank,
+    ts_headline('english', translate(body, chr(2) || chr(3), ''), websearch_to_tsquery('english', $1),
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3))::text AS snippet
+FROM chirps
+WHERE to_tsvector('english', body) @@ websearch_to_tsquery('english', $1)
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = $2::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = $2::uuid AND follows.followee_id = chirps.user_id)))
+ORDER BY rank DESC, created_at DESC
+LIMIT $3
+`
+
+type SearchChirpsParams struct {
+	Query     string
+	ViewerID  uuid.NullUUID
+	PageLimit int32
+}
+
+type SearchChirpsRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Body          string
+	UserID        uuid.UUID
+	ParentChirpID uuid.NullUUID
+	RepostOfID    uuid.NullUUID
+	Visibility    string
+	Rank          float32
+	Snippet       string
+}
+
+// matches in snippet are wrapped in the control characters chr(2) and chr(3),
+// which are stripped from the body first, so the caller can escape the body
+// before turning them into markup
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps, arg.Query, arg.ViewerID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentChirpID,
+			&i.RepostOfID,
+			&i.Visibility,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -132,3 +700,42 @@ func (q *Queries) GetChirpsById(ctx context.Context, userID uuid.UUID) ([]Chirp,
 	}
 	return items, nil
 }
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps SET deleted_at = NOW()
+WHERE (id = $1 OR (repost_of_id = $1 AND body = ''))
+    AND deleted_at IS NULL
+`
+
+// plain rechirps of the chirp go with it and share its deleted_at, so they
+// can be restored together
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps SET body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility, deleted_at
+`
+
+type UpdateChirpParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentChirpID,
+		&i.RepostOfID,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,345 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: drafts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO drafts (user_id, body, parent_chirp_id, media_ids, publish_at, visibility)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error
+`
+
+type CreateDraftParams struct {
+	UserID        uuid.UUID
+	Body          string
+	ParentChirpID uuid.NullUUID
+	MediaIds      []uuid.UUID
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, createDraft,
+		arg.UserID,
+		arg.Body,
+		arg.ParentChirpID,
+		pq.Array(arg.MediaIds),
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.ParentChirpID,
+		pq.Array(&i.MediaIds),
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.PublishError,
+	)
+	return i, err
+}
+
+const deleteDraft = `-- name: DeleteDraft :exec
+DELETE FROM drafts WHERE id = $1
+`
+
+func (q *Queries) DeleteDraft(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteDraft, id)
+	return err
+}
+
+const deleteScheduledDraft = `-- name: DeleteScheduledDraft :execrows
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2 AND publish_at IS NOT NULL
+`
+
+type DeleteScheduledDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteScheduledDraft(ctx context.Context, arg DeleteScheduledDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledDraft, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserDraft = `-- name: DeleteUserDraft :execrows
+DELETE FROM drafts WHERE id = $1 AND user_id = $2
+`
+
+type DeleteUserDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteUserDraft(ctx context.Context, arg DeleteUserDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserDraft, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const failScheduledDraft = `-- name: FailScheduledDraft :exec
+UPDATE drafts
+SET publish_attempts = publish_attempts + 1,
+    publish_error = $1::text,
+    publish_at = CASE WHEN publish_attempts + 1 < $2::int
+        THEN NOW() + (publish_attempts + 1) * INTERVAL '1 minute' END,
+    updated_at = NOW()
+WHERE id = $3 AND publish_at IS NOT NULL
+`
+
+type FailScheduledDraftParams struct {
+	PublishError string
+	MaxAttempts  int32
+	ID           uuid.UUID
+}
+
+// The draft is retried a few times with a growing delay, then taken off the
+// schedule and kept as a plain draft.
+func (q *Queries) FailScheduledDraft(ctx context.Context, arg FailScheduledDraftParams) error {
+	_, err := q.db.ExecContext(ctx, failScheduledDraft, arg.PublishError, arg.MaxAttempts, arg.ID)
+	return err
+}
+
+const getDraft = `-- name: GetDraft :one
+SELECT id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error FROM drafts WHERE id = $1 AND user_id = $2
+`
+
+type GetDraftParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraft(ctx context.Context, arg GetDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDraft, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.ParentChirpID,
+		pq.Array(&i.MediaIds),
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.PublishError,
+	)
+	return i, err
+}
+
+const getDraftForUpdate = `-- name: GetDraftForUpdate :one
+SELECT id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error FROM drafts WHERE id = $1 AND user_id = $2
+FOR UPDATE
+`
+
+type GetDraftForUpdateParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDraftForUpdate(ctx context.Context, arg GetDraftForUpdateParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDraftForUpdate, arg.ID, arg.UserID)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.ParentChirpID,
+		pq.Array(&i.MediaIds),
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.PublishError,
+	)
+	return i, err
+}
+
+const getDrafts = `-- name: GetDrafts :many
+SELECT id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC, id DESC
+`
+
+func (q *Queries) GetDrafts(ctx context.Context, userID uuid.UUID) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, getDrafts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+			&i.ParentChirpID,
+			pq.Array(&i.MediaIds),
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.PublishError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueDraft = `-- name: GetDueDraft :one
+SELECT id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error FROM drafts
+WHERE publish_at <= $1::timestamp
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED
+`
+
+// SKIP LOCKED lets several instances publish concurrently without taking
+// the same draft twice.
+func (q *Queries) GetDueDraft(ctx context.Context, now time.Time) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, getDueDraft, now)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.ParentChirpID,
+		pq.Array(&i.MediaIds),
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.PublishError,
+	)
+	return i, err
+}
+
+const getScheduledDrafts = `-- name: GetScheduledDrafts :many
+SELECT id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error FROM drafts
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC, id ASC
+`
+
+func (q *Queries) GetScheduledDrafts(ctx context.Context, userID uuid.UUID) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledDrafts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Body,
+			&i.ParentChirpID,
+			pq.Array(&i.MediaIds),
+			&i.PublishAt,
+			&i.Visibility,
+			&i.PublishAttempts,
+			&i.PublishError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unscheduleDraft = `-- name: UnscheduleDraft :exec
+UPDATE drafts SET publish_at = NULL, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) UnscheduleDraft(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unscheduleDraft, id)
+	return err
+}
+
+const updateDraft = `-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $3, parent_chirp_id = $4, media_ids = $5, publish_at = $6, visibility = $7,
+    publish_attempts = 0, publish_error = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING id, created_at, updated_at, user_id, body, parent_chirp_id, media_ids, publish_at, visibility, publish_attempts, publish_error
+`
+
+type UpdateDraftParams struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	ParentChirpID uuid.NullUUID
+	MediaIds      []uuid.UUID
+	PublishAt     sql.NullTime
+	Visibility    string
+}
+
+func (q *Queries) UpdateDraft(ctx context.Context, arg UpdateDraftParams) (Draft, error) {
+	row := q.db.QueryRowContext(ctx, updateDraft,
+		arg.ID,
+		arg.UserID,
+		arg.Body,
+		arg.ParentChirpID,
+		pq.Array(arg.MediaIds),
+		arg.PublishAt,
+		arg.Visibility,
+	)
+	var i Draft
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Body,
+		&i.ParentChirpID,
+		pq.Array(&i.MediaIds),
+		&i.PublishAt,
+		&i.Visibility,
+		&i.PublishAttempts,
+		&i.PublishError,
+	)
+	return i, err
+}
@@ -0,0 +1,85 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: email_verification.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const consumeVerificationToken = `-- name: ConsumeVerificationToken :one
+DELETE FROM email_verification_tokens
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id, email
+`
+
+type ConsumeVerificationTokenRow struct {
+	UserID uuid.UUID
+	Email  string
+}
+
+func (q *Queries) ConsumeVerificationToken(ctx context.Context, tokenHash string) (ConsumeVerificationTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, consumeVerificationToken, tokenHash)
+	var i ConsumeVerificationTokenRow
+	err := row.Scan(&i.UserID, &i.Email)
+	return i, err
+}
+
+const createVerificationToken = `-- name: CreateVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW() + $4::int * INTERVAL '1 second'
+)
+`
+
+type CreateVerificationTokenParams struct {
+	TokenHash  string
+	UserID     uuid.UUID
+	Email      string
+	TtlSeconds int32
+}
+
+func (q *Queries) CreateVerificationToken(ctx context.Context, arg CreateVerificationTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createVerificationToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.Email,
+		arg.TtlSeconds,
+	)
+	return err
+}
+
+const deleteUserVerificationTokens = `-- name: DeleteUserVerificationTokens :exec
+DELETE FROM email_verification_tokens WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserVerificationTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserVerificationTokens, userID)
+	return err
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND email = $2
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+// a token sent to an address the user has since changed no longer counts
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
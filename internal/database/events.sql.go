@@ -0,0 +1,30 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: events.sql
+
+package database
+
+import (
+	"context"
+)
+
+const nextEventId = `-- name: NextEventId :one
+SELECT nextval('chirp_events_id_seq')::bigint AS id
+`
+
+func (q *Queries) NextEventId(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, nextEventId)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const notifyEvent = `-- name: NotifyEvent :exec
+SELECT pg_notify('chirp_events', $1::text)
+`
+
+func (q *Queries) NotifyEvent(ctx context.Context, payload string) error {
+	_, err := q.db.ExecContext(ctx, notifyEvent, payload)
+	return err
+}
@@ -0,0 +1,138 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: links.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addChirpLink = `-- name: AddChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type AddChirpLinkParams struct {
+	ChirpID  uuid.UUID
+	Url      string
+	Position int32
+}
+
+func (q *Queries) AddChirpLink(ctx context.Context, arg AddChirpLinkParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpLink, arg.ChirpID, arg.Url, arg.Position)
+	return err
+}
+
+const deleteChirpLinks = `-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpLinks(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpLinks, chirpID)
+	return err
+}
+
+const getChirpLinkPreviews = `-- name: GetChirpLinkPreviews :many
+SELECT DISTINCT ON (chirp_links.chirp_id)
+    chirp_links.chirp_id,
+    link_previews.url,
+    link_previews.title,
+    link_previews.description,
+    link_previews.image_url,
+    link_previews.site_name
+FROM chirp_links
+JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY($1::uuid[])
+AND NOT link_previews.failed
+ORDER BY chirp_links.chirp_id, chirp_links.position ASC
+`
+
+type GetChirpLinkPreviewsRow struct {
+	ChirpID     uuid.UUID
+	Url         string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+}
+
+func (q *Queries) GetChirpLinkPreviews(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpLinkPreviewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpLinkPreviews, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpLinkPreviewsRow
+	for rows.Next() {
+		var i GetChirpLinkPreviewsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Url,
+			&i.Title,
+			&i.Description,
+			&i.ImageUrl,
+			&i.SiteName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkPreviewFetchedAt = `-- name: GetLinkPreviewFetchedAt :one
+SELECT fetched_at FROM link_previews WHERE url = $1
+`
+
+func (q *Queries) GetLinkPreviewFetchedAt(ctx context.Context, url string) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLinkPreviewFetchedAt, url)
+	var fetched_at time.Time
+	err := row.Scan(&fetched_at)
+	return fetched_at, err
+}
+
+const upsertLinkPreview = `-- name: UpsertLinkPreview :exec
+INSERT INTO link_previews (url, title, description, image_url, site_name, failed, fetched_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (url) DO UPDATE SET
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    image_url = EXCLUDED.image_url,
+    site_name = EXCLUDED.site_name,
+    failed = EXCLUDED.failed,
+    fetched_at = EXCLUDED.fetched_at
+`
+
+type UpsertLinkPreviewParams struct {
+	Url         string
+	Title       string
+	Description string
+	ImageUrl    string
+	SiteName    string
+	Failed      bool
+}
+
+func (q *Queries) UpsertLinkPreview(ctx context.Context, arg UpsertLinkPreviewParams) error {
+	_, err := q.db.ExecContext(ctx, upsertLinkPreview,
+		arg.Url,
+		arg.Title,
+		arg.Description,
+		arg.ImageUrl,
+		arg.SiteName,
+		arg.Failed,
+	)
+	return err
+}
@@ -0,0 +1,288 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attachMediaFiles = `-- name: AttachMediaFiles :execrows
+UPDATE media_files
+SET chirp_id = $1::uuid,
+    position = array_position($2::uuid[], id)
+WHERE id = ANY($2::uuid[])
+AND user_id = $3
+AND chirp_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+`
+
+type AttachMediaFilesParams struct {
+	ChirpID  uuid.UUID
+	MediaIds []uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) AttachMediaFiles(ctx context.Context, arg AttachMediaFilesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, attachMediaFiles, arg.ChirpID, pq.Array(arg.MediaIds), arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countAttachableMediaFiles = `-- name: CountAttachableMediaFiles :one
+SELECT COUNT(*) FROM media_files
+WHERE id = ANY($1::uuid[])
+AND user_id = $2
+AND chirp_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+`
+
+type CountAttachableMediaFilesParams struct {
+	MediaIds []uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) CountAttachableMediaFiles(ctx context.Context, arg CountAttachableMediaFilesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAttachableMediaFiles, pq.Array(arg.MediaIds), arg.UserID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createMediaFile = `-- name: CreateMediaFile :one
+INSERT INTO media_files (id, user_id, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key
+`
+
+type CreateMediaFileParams struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ContentType  string
+	SizeBytes    int64
+	Width        int32
+	Height       int32
+	StorageKey   string
+	ThumbnailKey string
+}
+
+func (q *Queries) CreateMediaFile(ctx context.Context, arg CreateMediaFileParams) (MediaFile, error) {
+	row := q.db.QueryRowContext(ctx, createMediaFile,
+		arg.ID,
+		arg.UserID,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.Width,
+		arg.Height,
+		arg.StorageKey,
+		arg.ThumbnailKey,
+	)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+	)
+	return i, err
+}
+
+const deleteOrphanedMediaFiles = `-- name: DeleteOrphanedMediaFiles :many
+DELETE FROM media_files
+WHERE chirp_id IS NULL
+AND created_at < $1::timestamp
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+AND NOT EXISTS (SELECT 1 FROM drafts WHERE media_files.id = ANY(drafts.media_ids))
+RETURNING id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key
+`
+
+// Uploads that never made it onto a chirp, once no draft or profile refers
+// to them any more.
+func (q *Queries) DeleteOrphanedMediaFiles(ctx context.Context, cutoff time.Time) ([]MediaFile, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOrphanedMediaFiles, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaFile
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePurgedMediaFiles = `-- name: DeletePurgedMediaFiles :many
+DELETE FROM media_files
+WHERE chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < $1::timestamp
+)
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+RETURNING id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key
+`
+
+func (q *Queries) DeletePurgedMediaFiles(ctx context.Context, cutoff time.Time) ([]MediaFile, error) {
+	rows, err := q.db.QueryContext(ctx, deletePurgedMediaFiles, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaFile
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAvatarCandidate = `-- name: GetAvatarCandidate :one
+SELECT id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key FROM media_files WHERE id = $1 AND user_id = $2 AND chirp_id IS NULL
+`
+
+type GetAvatarCandidateParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// An avatar can't also be attached to a chirp, or purging the chirp would
+// take the avatar file with it.
+func (q *Queries) GetAvatarCandidate(ctx context.Context, arg GetAvatarCandidateParams) (MediaFile, error) {
+	row := q.db.QueryRowContext(ctx, getAvatarCandidate, arg.ID, arg.UserID)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+	)
+	return i, err
+}
+
+const getChirpMediaFiles = `-- name: GetChirpMediaFiles :many
+SELECT id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key FROM media_files
+WHERE chirp_id = ANY($1::uuid[])
+ORDER BY chirp_id, position ASC
+`
+
+func (q *Queries) GetChirpMediaFiles(ctx context.Context, chirpIds []uuid.UUID) ([]MediaFile, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMediaFiles, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MediaFile
+	for rows.Next() {
+		var i MediaFile
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UserID,
+			&i.ChirpID,
+			&i.Position,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.Width,
+			&i.Height,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaFile = `-- name: GetMediaFile :one
+SELECT id, created_at, user_id, chirp_id, position, content_type, size_bytes, width, height, storage_key, thumbnail_key FROM media_files WHERE id = $1
+`
+
+func (q *Queries) GetMediaFile(ctx context.Context, id uuid.UUID) (MediaFile, error) {
+	row := q.db.QueryRowContext(ctx, getMediaFile, id)
+	var i MediaFile
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ChirpID,
+		&i.Position,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.Width,
+		&i.Height,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+	)
+	return i, err
+}
@@ -0,0 +1,129 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: notifications.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createNotification = `-- name: CreateNotification :exec
+INSERT INTO notifications (user_id, actor_id, type, chirp_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+`
+
+type CreateNotificationParams struct {
+	UserID  uuid.UUID
+	ActorID uuid.UUID
+	Type    string
+	ChirpID uuid.NullUUID
+}
+
+// either the per-chirp or the chirpless dedupe index may conflict
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) error {
+	_, err := q.db.ExecContext(ctx, createNotification,
+		arg.UserID,
+		arg.ActorID,
+		arg.Type,
+		arg.ChirpID,
+	)
+	return err
+}
+
+const getNotifications = `-- name: GetNotifications :many
+SELECT id, user_id, actor_id, type, chirp_id, created_at, read_at FROM notifications
+WHERE notifications.user_id = $1
+    AND (notifications.chirp_id IS NULL OR EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = notifications.chirp_id
+            AND chirps.deleted_at IS NULL
+            AND (chirps.visibility = 'public'
+                OR chirps.user_id = notifications.user_id
+                OR (chirps.visibility = 'followers' AND EXISTS (
+                    SELECT 1 FROM follows
+                    WHERE follows.follower_id = notifications.user_id AND follows.followee_id = chirps.user_id)))))
+    AND (NOT $2::boolean OR notifications.read_at IS NULL)
+    AND ($3::timestamp IS NULL
+        OR (notifications.created_at, notifications.id) < ($3::timestamp, $4::uuid))
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT $5
+`
+
+type GetNotificationsParams struct {
+	UserID          uuid.UUID
+	UnreadOnly      bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+// notifications about chirps that were deleted or that the user can no
+// longer see are left out
+func (q *Queries) GetNotifications(ctx context.Context, arg GetNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, getNotifications,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ActorID,
+			&i.Type,
+			&i.ChirpID,
+			&i.CreatedAt,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markAllNotificationsRead, userID)
+	return err
+}
+
+const markNotificationsRead = `-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL AND id = ANY($2::uuid[])
+`
+
+type MarkNotificationsReadParams struct {
+	UserID uuid.UUID
+	Ids    []uuid.UUID
+}
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, arg MarkNotificationsReadParams) error {
+	_, err := q.db.ExecContext(ctx, markNotificationsRead, arg.UserID, pq.Array(arg.Ids))
+	return err
+}
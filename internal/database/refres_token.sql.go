@@ -40,7 +40,7 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT user_id, expires_at FROM refresh_tokens WHERE token = $1
+SELECT user_id, expires_at FROM refresh_tokens WHERE token = $1 AND revoked_at IS NULL
 `
 
 type GetRefreshTokenRow struct {
@@ -73,3 +73,13 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) (RefreshToken,
 	)
 	return i, err
 }
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserRefreshTokens, userID)
+	return err
+}
@@ -0,0 +1,133 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: tags.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addChirpTag = `-- name: AddChirpTag :exec
+INSERT INTO chirp_tags (chirp_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type AddChirpTagParams struct {
+	ChirpID uuid.UUID
+	TagID   uuid.UUID
+}
+
+func (q *Queries) AddChirpTag(ctx context.Context, arg AddChirpTagParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpTag, arg.ChirpID, arg.TagID)
+	return err
+}
+
+const deleteChirpTags = `-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpTags(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpTags, chirpID)
+	return err
+}
+
+const getChirpTags = `-- name: GetChirpTags :many
+SELECT chirp_tags.chirp_id, tags.name FROM chirp_tags
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE chirp_tags.chirp_id = ANY($1::uuid[])
+ORDER BY tags.name ASC
+`
+
+type GetChirpTagsRow struct {
+	ChirpID uuid.UUID
+	Name    string
+}
+
+func (q *Queries) GetChirpTags(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpTags, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpTagsRow
+	for rows.Next() {
+		var i GetChirpTagsRow
+		if err := rows.Scan(&i.ChirpID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTrendingTags = `-- name: GetTrendingTags :many
+SELECT tags.name, COUNT(*) AS chirp_count FROM chirp_tags
+JOIN tags ON tags.id = chirp_tags.tag_id
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirps.created_at > $1
+    AND chirps.visibility = 'public'
+    AND chirps.deleted_at IS NULL
+GROUP BY tags.name
+ORDER BY chirp_count DESC, tags.name ASC
+LIMIT $2
+`
+
+type GetTrendingTagsParams struct {
+	Since     time.Time
+	PageLimit int32
+}
+
+type GetTrendingTagsRow struct {
+	Name       string
+	ChirpCount int64
+}
+
+func (q *Queries) GetTrendingTags(ctx context.Context, arg GetTrendingTagsParams) ([]GetTrendingTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTrendingTags, arg.Since, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTrendingTagsRow
+	for rows.Next() {
+		var i GetTrendingTagsRow
+		if err := rows.Scan(&i.Name, &i.ChirpCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertTag = `-- name: UpsertTag :one
+INSERT INTO tags (name)
+VALUES ($1)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id
+`
+
+func (q *Queries) UpsertTag(ctx context.Context, name string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, upsertTag, name)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
@@ -7,29 +7,34 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES(
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, pinned_chirp_id, display_name, bio, avatar_media_id, email_verified
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,6 +43,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.PinnedChirpID,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -52,7 +63,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, pinned_chirp_id, display_name, bio, avatar_media_id, email_verified FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
@@ -65,12 +76,18 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.PinnedChirpID,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users WHERE id = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, pinned_chirp_id, display_name, bio, avatar_media_id, email_verified FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
@@ -83,10 +100,145 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.PinnedChirpID,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
+const getUserIdByHandle = `-- name: GetUserIdByHandle :one
+SELECT id FROM users WHERE handle = $1
+`
+
+func (q *Queries) GetUserIdByHandle(ctx context.Context, handle sql.NullString) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getUserIdByHandle, handle)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_media_id,
+    users.is_chirpy_red, users.pinned_chirp_id,
+    (SELECT COUNT(*) FROM chirps
+        WHERE chirps.user_id = users.id
+            AND chirps.deleted_at IS NULL
+            AND (chirps.visibility = 'public'
+                OR chirps.user_id = $1::uuid
+                OR (chirps.visibility = 'followers' AND EXISTS (
+                    SELECT 1 FROM follows
+                    WHERE follows.follower_id = $1::uuid AND follows.followee_id = chirps.user_id)))
+    ) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.id = $2
+`
+
+type GetUserProfileParams struct {
+	ViewerID uuid.NullUUID
+	ID       uuid.UUID
+}
+
+type GetUserProfileRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarMediaID  uuid.NullUUID
+	IsChirpyRed    bool
+	PinnedChirpID  uuid.NullUUID
+	ChirpCount     int64
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+// chirp_count only counts chirps the viewer is allowed to see
+func (q *Queries) GetUserProfile(ctx context.Context, arg GetUserProfileParams) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserProfile, arg.ViewerID, arg.ID)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.IsChirpyRed,
+		&i.PinnedChirpID,
+		&i.ChirpCount,
+		&i.FollowerCount,
+		&i.FollowingCount,
+	)
+	return i, err
+}
+
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, handle::text AS handle FROM users WHERE handle = ANY($1::text[])
+`
+
+type GetUsersByHandlesRow struct {
+	ID     uuid.UUID
+	Handle string
+}
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]GetUsersByHandlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByHandlesRow
+	for rows.Next() {
+		var i GetUsersByHandlesRow
+		if err := rows.Scan(&i.ID, &i.Handle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinChirp = `-- name: PinChirp :exec
+UPDATE users SET pinned_chirp_id = $1::uuid WHERE id = $2
+`
+
+type PinChirpParams struct {
+	ChirpID uuid.UUID
+	ID      uuid.UUID
+}
+
+func (q *Queries) PinChirp(ctx context.Context, arg PinChirpParams) error {
+	_, err := q.db.ExecContext(ctx, pinChirp, arg.ChirpID, arg.ID)
+	return err
+}
+
+const unpinChirp = `-- name: UnpinChirp :exec
+UPDATE users SET pinned_chirp_id = NULL
+WHERE id = $1 AND pinned_chirp_id = $2::uuid
+`
+
+type UnpinChirpParams struct {
+	ID      uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) UnpinChirp(ctx context.Context, arg UnpinChirpParams) error {
+	_, err := q.db.ExecContext(ctx, unpinChirp, arg.ID, arg.ChirpID)
+	return err
+}
+
 const updateChirpyRed = `-- name: UpdateChirpyRed :exec
 UPDATE users SET is_chirpy_red = TRUE WHERE id = $1
 `
@@ -97,15 +249,23 @@ func (q *Queries) UpdateChirpyRed(ctx context.Context, id uuid.UUID) error {
 }
 
 const updateUser = `-- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+UPDATE users SET
+    email = COALESCE($1, email),
+    email_verified = CASE WHEN $1::text IS NULL OR $1::text = email THEN email_verified ELSE FALSE END,
+    hashed_password = COALESCE($2, hashed_password),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, pinned_chirp_id, display_name, bio, avatar_media_id, email_verified
 `
 
 type UpdateUserParams struct {
-	Email          string
-	HashedPassword string
+	Email          sql.NullString
+	HashedPassword sql.NullString
 	ID             uuid.UUID
 }
 
+// only the fields that are supplied change; a new email has to be verified
+// again
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
 	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
 	var i User
@@ -116,6 +276,61 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.PinnedChirpID,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.EmailVerified,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users SET
+    handle = COALESCE($1, handle),
+    display_name = COALESCE($2, display_name),
+    bio = COALESCE($3, bio),
+    avatar_media_id = CASE WHEN $4::boolean THEN $5::uuid ELSE avatar_media_id END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $6
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, pinned_chirp_id, display_name, bio, avatar_media_id, email_verified
+`
+
+type UpdateUserProfileParams struct {
+	Handle        sql.NullString
+	DisplayName   sql.NullString
+	Bio           sql.NullString
+	SetAvatar     bool
+	AvatarMediaID uuid.NullUUID
+	ID            uuid.UUID
+}
+
+// only the fields that are supplied change; set_avatar distinguishes
+// clearing the avatar from leaving it alone
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.SetAvatar,
+		arg.AvatarMediaID,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.PinnedChirpID,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarMediaID,
+		&i.EmailVerified,
 	)
 	return i, err
 }
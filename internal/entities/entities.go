@@ -0,0 +1,65 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a tag or mention must start the body or follow a non-word character, so
+// emails and things like "a#b" are not picked up
+var (
+	hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])#([\p{L}\p{N}_]{1,50})`)
+	mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@([A-Za-z0-9_]{1,30})`)
+	handleRegex  = regexp.MustCompile(`^[A-Za-z0-9_]{1,30}$`)
+	urlRegex     = regexp.MustCompile(`https?://[^\s<>"]+`)
+)
+
+// MaxURLs is how many links are taken from one chirp.
+const MaxURLs = 4
+
+func ValidHandle(handle string) bool {
+	return handleRegex.MatchString(handle)
+}
+
+// Hashtags returns the distinct lowercased hashtags in body, without the #.
+func Hashtags(body string) []string {
+	return extract(hashtagRegex, body)
+}
+
+// Mentions returns the distinct lowercased handles mentioned in body,
+// without the @.
+func Mentions(body string) []string {
+	return extract(mentionRegex, body)
+}
+
+// URLs returns the distinct http(s) links in body in order of appearance.
+// Trailing punctuation is treated as part of the sentence, not the link.
+func URLs(body string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	for _, match := range urlRegex.FindAllString(body, -1) {
+		link := strings.TrimRight(match, ".,;:!?)]}'")
+		if seen[link] || len(found) == MaxURLs {
+			continue
+		}
+		seen[link] = true
+		found = append(found, link)
+	}
+
+	return found
+}
+
+func extract(re *regexp.Regexp, body string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	for _, match := range re.FindAllStringSubmatch(body, -1) {
+		val := strings.ToLower(match[1])
+		if seen[val] {
+			continue
+		}
+		seen[val] = true
+		found = append(found, val)
+	}
+
+	return found
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"david-galdamez/chirp/models"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ChirpCreated = "chirp.created"
+	ChirpDeleted = "chirp.deleted"
+	UserUpgraded = "user.upgraded"
+
+	// BannedWordsChanged tells other instances to reload the banned word
+	// list. It is handled by ListenPostgres and never reaches subscribers.
+	BannedWordsChanged = "banned_words.changed"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// the hub gives up on it.
+const subscriberBuffer = 64
+
+type Event struct {
+	ID      uint64        `json:"id"`
+	Type    string        `json:"type"`
+	ChirpID uuid.UUID     `json:"chirp_id"`
+	UserID  uuid.UUID     `json:"user_id"`
+	Chirp   *models.Chirp `json:"chirp"`
+}
+
+// Subscription receives events on C. C is closed when the subscriber is
+// unsubscribed or dropped for being too slow; in the latter case the client
+// is expected to reconnect and resume from the last event it saw.
+type Subscription struct {
+	C  <-chan Event
+	ch chan Event
+}
+
+// Hub fans chirp lifecycle events out to in-process subscribers and keeps a
+// bounded history so reconnecting clients can resume.
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		nextID:      1,
+		historySize: historySize,
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish delivers an event to every subscriber, assigning it the next
+// local id unless it already carries one. Subscribers whose buffer is full
+// are dropped instead of blocking the publisher.
+func (h *Hub) Publish(event Event) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.ID == 0 {
+		event.ID = h.nextID
+	}
+	if event.ID >= h.nextID {
+		h.nextID = event.ID + 1
+	}
+
+	h.history = append(h.history, event)
+	if len(h.history) > h.historySize {
+		h.history = h.history[len(h.history)-h.historySize:]
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe registers a new subscriber. Events newer than lastEventID that
+// are still in the history are returned so the caller can replay them
+// before reading from the subscription.
+func (h *Hub) Subscribe(lastEventID uint64) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := []Event{}
+	if lastEventID > 0 && lastEventID < h.nextID {
+		for _, event := range h.history {
+			if event.ID > lastEventID {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch}
+	h.subscribers[sub] = struct{}{}
+
+	return sub, backlog
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,61 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres NOTIFY channel chirp events are published on.
+const Channel = "chirp_events"
+
+// ListenPostgres feeds events published with NOTIFY on Channel by any
+// instance into hub. It blocks, reconnecting as needed, so run it in its own
+// goroutine. Events sent while the connection is down are lost.
+//
+// BannedWordsChanged events call onBannedWords instead. It is also called
+// after a reconnect, since a change may have been missed.
+func ListenPostgres(dbURL string, hub *Hub, onBannedWords func()) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	// this instance's own events come back through the channel too, so
+	// giving up here would leave its clients without any
+	for delay := time.Second; ; delay = min(delay*2, time.Minute) {
+		err := listener.Listen(Channel)
+		if err == nil || errors.Is(err, pq.ErrChannelAlreadyOpen) {
+			break
+		}
+
+		log.Printf("Error listening for events, retrying in %v: %v", delay, err)
+		time.Sleep(delay)
+	}
+
+	for notification := range listener.Notify {
+		// a nil notification means the connection was re-established
+		if notification == nil {
+			onBannedWords()
+			continue
+		}
+
+		event := Event{}
+		if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+			log.Printf("Error decoding event: %v", err)
+			continue
+		}
+
+		if event.Type == BannedWordsChanged {
+			onBannedWords()
+			continue
+		}
+
+		hub.Publish(event)
+	}
+}
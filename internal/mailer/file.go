@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// File appends every message to a file instead of sending it, for local
+// development and testing.
+type File struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+func NewFile(path, from string) *File {
+	return &File{path: path, from: from}
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(format(f.from, msg), "\r\n"...)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
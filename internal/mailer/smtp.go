@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// sendTimeout bounds a whole SMTP exchange so a slow or silent server can't
+// hold up the request that triggered the email.
+const sendTimeout = 10 * time.Second
+
+// SMTP sends mail through an SMTP server, authenticating with PLAIN auth
+// when a username is set.
+type SMTP struct {
+	host string
+	addr string
+	from string
+	// envelope is the bare address of from, as SMTP's MAIL FROM needs it
+	envelope string
+	auth     smtp.Auth
+}
+
+func NewSMTP(host, port, username, password, from string) *SMTP {
+	s := &SMTP{
+		host:     host,
+		addr:     net.JoinHostPort(host, port),
+		from:     from,
+		envelope: from,
+	}
+
+	if addr, err := mail.ParseAddress(from); err == nil {
+		s.envelope = addr.Address
+	}
+
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return s
+}
+
+// Send delivers msg the way smtp.SendMail does, but gives up once ctx is
+// done or sendTimeout has passed, whichever comes first.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, so the deadline is put on the
+	// connection and a cancelled ctx closes it
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+
+	if s.auth != nil {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(s.auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := client.Mail(s.envelope); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(format(s.from, msg)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,161 @@
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+func ParseLimit(raw string) (int, error) {
+	if raw == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	return limit, nil
+}
+
+func EncodeCursor(key time.Time, id uuid.UUID) string {
+	raw := key.UTC().Format(time.RFC3339Nano) + "," + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	val, err := decodeFields(cursor, 2)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+
+	return parseKeyAndID(val[0], val[1])
+}
+
+// decodeFields splits a cursor into exactly n comma separated fields.
+func decodeFields(cursor string, n int) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	val := strings.Split(string(data), ",")
+	if len(val) != n {
+		return nil, errors.New("malformed cursor")
+	}
+
+	return val, nil
+}
+
+func parseKeyAndID(rawKey, rawId string) (time.Time, uuid.UUID, error) {
+	key, err := time.Parse(time.RFC3339Nano, rawKey)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	id, err := uuid.Parse(rawId)
+	if err != nil {
+		return time.Time{}, uuid.Nil, errors.New("malformed cursor")
+	}
+
+	return key, id, nil
+}
+
+// ParseCursor decodes an optional cursor into the nullable query arguments
+// used by the keyset queries. An empty cursor yields null values.
+func ParseCursor(cursor string) (sql.NullTime, uuid.NullUUID, error) {
+	if cursor == "" {
+		return sql.NullTime{}, uuid.NullUUID{}, nil
+	}
+
+	key, id, err := DecodeCursor(cursor)
+	if err != nil {
+		return sql.NullTime{}, uuid.NullUUID{}, err
+	}
+
+	return sql.NullTime{Time: key, Valid: true}, uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+// ErrSortMismatch is returned when a cursor is reused with a different sort
+// than the page it came from, which would apply the keyset predicate to the
+// wrong column or direction.
+var ErrSortMismatch = errors.New("cursor does not match the requested sort")
+
+// SortKey is the value of the sort column a sorted cursor resumes after.
+// Listings ordered by a timestamp set Time, those ordered by a count set
+// Count.
+type SortKey struct {
+	Time  sql.NullTime
+	Count sql.NullInt64
+}
+
+func TimeKey(t time.Time) SortKey {
+	return SortKey{Time: sql.NullTime{Time: t, Valid: true}}
+}
+
+func CountKey(n int64) SortKey {
+	return SortKey{Count: sql.NullInt64{Int64: n, Valid: true}}
+}
+
+// EncodeSortedCursor is EncodeCursor for listings with a selectable sort,
+// recording the sort so the next request can be checked against it.
+func EncodeSortedCursor(key SortKey, id uuid.UUID, sort string) string {
+	rawKey := key.Time.Time.UTC().Format(time.RFC3339Nano)
+	if key.Count.Valid {
+		rawKey = strconv.FormatInt(key.Count.Int64, 10)
+	}
+
+	raw := rawKey + "," + id.String() + "," + sort
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseSortedCursor decodes an optional cursor made by EncodeSortedCursor,
+// returning ErrSortMismatch when it was made for a different sort. An empty
+// cursor yields a zero SortKey and a null id.
+func ParseSortedCursor(cursor, sort string) (SortKey, uuid.NullUUID, error) {
+	if cursor == "" {
+		return SortKey{}, uuid.NullUUID{}, nil
+	}
+
+	val, err := decodeFields(cursor, 3)
+	if err != nil {
+		return SortKey{}, uuid.NullUUID{}, err
+	}
+
+	if val[2] != sort {
+		return SortKey{}, uuid.NullUUID{}, ErrSortMismatch
+	}
+
+	id, err := uuid.Parse(val[1])
+	if err != nil {
+		return SortKey{}, uuid.NullUUID{}, errors.New("malformed cursor")
+	}
+
+	// a timestamp never parses as an integer, so the key's kind needs no
+	// marker of its own
+	key := SortKey{}
+	if count, err := strconv.ParseInt(val[0], 10, 64); err == nil {
+		key = CountKey(count)
+	} else if t, err := time.Parse(time.RFC3339Nano, val[0]); err == nil {
+		key = TimeKey(t)
+	} else {
+		return SortKey{}, uuid.NullUUID{}, errors.New("malformed cursor")
+	}
+
+	return key, uuid.NullUUID{UUID: id, Valid: true}, nil
+}
@@ -0,0 +1,129 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{"", DefaultLimit, false},
+		{"1", 1, false},
+		{"50", 50, false},
+		{"1000", MaxLimit, false},
+		{"0", 0, true},
+		{"-3", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseLimit(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %d, want %d", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	id := uuid.New()
+
+	gotKey, gotId, err := ParseCursor(EncodeCursor(key, id))
+	if err != nil {
+		t.Fatalf("ParseCursor() error = %v", err)
+	}
+	if !gotKey.Valid || !gotKey.Time.Equal(key) || gotId.UUID != id {
+		t.Errorf("ParseCursor() = %v, %v, want %v, %v", gotKey, gotId, key, id)
+	}
+
+	gotKey, gotId, err = ParseCursor("")
+	if err != nil || gotKey.Valid || gotId.Valid {
+		t.Errorf("ParseCursor(\"\") = %v, %v, %v, want null values", gotKey, gotId, err)
+	}
+}
+
+func TestSortedCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name string
+		key  SortKey
+		sort string
+	}{
+		{"created_at", TimeKey(time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)), "created_at:desc"},
+		{"updated_at", TimeKey(time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)), "updated_at:asc"},
+		{"like_count", CountKey(42), "like_count:desc"},
+		{"zero like_count", CountKey(0), "like_count:asc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey, gotId, err := ParseSortedCursor(EncodeSortedCursor(tt.key, id, tt.sort), tt.sort)
+			if err != nil {
+				t.Fatalf("ParseSortedCursor() error = %v", err)
+			}
+			if gotId != (uuid.NullUUID{UUID: id, Valid: true}) {
+				t.Errorf("ParseSortedCursor() id = %v, want %v", gotId, id)
+			}
+			if gotKey.Count != tt.key.Count || gotKey.Time.Valid != tt.key.Time.Valid || !gotKey.Time.Time.Equal(tt.key.Time.Time) {
+				t.Errorf("ParseSortedCursor() key = %+v, want %+v", gotKey, tt.key)
+			}
+		})
+	}
+}
+
+func TestParseSortedCursorEmpty(t *testing.T) {
+	key, id, err := ParseSortedCursor("", "like_count:desc")
+	if err != nil || key.Time.Valid || key.Count.Valid || id.Valid {
+		t.Errorf("ParseSortedCursor(\"\") = %+v, %v, %v, want null values", key, id, err)
+	}
+}
+
+func TestParseSortedCursorErrors(t *testing.T) {
+	id := uuid.New()
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		want   error
+	}{
+		{"other direction", EncodeSortedCursor(CountKey(3), id, "like_count:asc"), "like_count:desc", ErrSortMismatch},
+		{"other key", EncodeSortedCursor(TimeKey(time.Now()), id, "created_at:desc"), "like_count:desc", ErrSortMismatch},
+		{"unsorted cursor", EncodeCursor(time.Now(), id), "created_at:desc", nil},
+		{"not base64", "%%%", "created_at:desc", nil},
+		{"bad key", encode("yesterday," + id.String() + ",created_at:desc"), "created_at:desc", nil},
+		{"bad id", encode("7,not-a-uuid,like_count:desc"), "like_count:desc", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseSortedCursor(tt.cursor, tt.sort)
+			if err == nil {
+				t.Fatalf("ParseSortedCursor() error = nil, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ParseSortedCursor() error = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && errors.Is(err, ErrSortMismatch) {
+				t.Errorf("ParseSortedCursor() error = %v, want a malformed cursor error", err)
+			}
+		})
+	}
+}
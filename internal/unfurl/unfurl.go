@@ -0,0 +1,176 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxBodySize is how much of a page is read looking for metadata.
+	maxBodySize = 1 << 20
+
+	maxTitleLength       = 200
+	maxDescriptionLength = 400
+)
+
+var (
+	ErrUnsupportedURL = errors.New("unsupported url")
+	ErrNotHTML        = errors.New("not an html page")
+	ErrNoMetadata     = errors.New("page has no title")
+)
+
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher reads OpenGraph and <title> metadata from web pages. The client is
+// injected so tests can point it at a local server; production code should
+// pass NewClient.
+type Fetcher struct {
+	client *http.Client
+}
+
+func New(client *http.Client) *Fetcher {
+	return &Fetcher{client: client}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrUnsupportedURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Chirpy-LinkPreview/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, ErrNotHTML
+	}
+
+	preview := parse(io.LimitReader(resp.Body, maxBodySize), resp.Request.URL)
+	if preview.Title == "" {
+		return nil, ErrNoMetadata
+	}
+	preview.URL = rawURL
+
+	return preview, nil
+}
+
+// parse reads metadata from the document head. OpenGraph tags win over
+// <title> and the description meta tag. Relative image URLs are resolved
+// against base.
+func parse(r io.Reader, base *url.URL) *Preview {
+	preview := &Preview{}
+	var title, description string
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finish(preview, title, description, base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "body":
+				return finish(preview, title, description, base)
+			case "title":
+				if tokenizer.Next() == html.TextToken && title == "" {
+					title = string(tokenizer.Text())
+				}
+			case "meta":
+				key, content := "", ""
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "property", "name":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+
+				switch key {
+				case "og:title":
+					preview.Title = content
+				case "og:description":
+					preview.Description = content
+				case "og:image":
+					preview.ImageURL = content
+				case "og:site_name":
+					preview.SiteName = content
+				case "description":
+					description = content
+				}
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				return finish(preview, title, description, base)
+			}
+		}
+	}
+}
+
+func finish(preview *Preview, title, description string, base *url.URL) *Preview {
+	if preview.Title == "" {
+		preview.Title = title
+	}
+	if preview.Description == "" {
+		preview.Description = description
+	}
+
+	preview.Title = clean(preview.Title, maxTitleLength)
+	preview.Description = clean(preview.Description, maxDescriptionLength)
+	preview.SiteName = clean(preview.SiteName, maxTitleLength)
+
+	if preview.ImageURL != "" {
+		image, err := base.Parse(preview.ImageURL)
+		if err != nil || (image.Scheme != "http" && image.Scheme != "https") {
+			preview.ImageURL = ""
+		} else {
+			preview.ImageURL = image.String()
+		}
+	}
+
+	if preview.SiteName == "" {
+		preview.SiteName = base.Hostname()
+	}
+
+	return preview
+}
+
+// clean collapses whitespace and truncates s to at most limit runes.
+func clean(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+
+	return string([]rune(s)[:limit-1]) + "…"
+}
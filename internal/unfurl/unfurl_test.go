@@ -0,0 +1,246 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is NewClient with a transport that may reach the loopback test
+// server, keeping its timeout and redirect policy.
+func testClient(srv *httptest.Server, timeout time.Duration) *http.Client {
+	client := NewClient(timeout)
+	client.Transport = srv.Client().Transport
+	return client
+}
+
+func serveHTML(page string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", serveHTML(`<html><head>
+		<title>Plain title</title>
+		<meta property="og:title" content="  OpenGraph   title ">
+		<meta property="og:description" content="OpenGraph description">
+		<meta property="og:image" content="/images/card.png">
+		<meta property="og:site_name" content="Example">
+		<meta name="description" content="Plain description">
+	</head><body></body></html>`))
+	mux.HandleFunc("/title", serveHTML(`<html><head>
+		<title>Plain title</title>
+		<meta name="description" content="Plain description">
+	</head><body><meta property="og:title" content="Too late"></body></html>`))
+	mux.HandleFunc("/empty", serveHTML(`<html><head></head><body>No title</body></html>`))
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"title": "not a page"}`)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := New(testClient(srv, time.Second))
+
+	t.Run("opengraph tags", func(t *testing.T) {
+		got, err := fetcher.Fetch(context.Background(), srv.URL+"/og")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		want := Preview{
+			URL:         srv.URL + "/og",
+			Title:       "OpenGraph title",
+			Description: "OpenGraph description",
+			ImageURL:    srv.URL + "/images/card.png",
+			SiteName:    "Example",
+		}
+		if *got != want {
+			t.Errorf("Fetch() = %+v, want %+v", *got, want)
+		}
+	})
+
+	t.Run("title fallback", func(t *testing.T) {
+		got, err := fetcher.Fetch(context.Background(), srv.URL+"/title")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		want := Preview{
+			URL:         srv.URL + "/title",
+			Title:       "Plain title",
+			Description: "Plain description",
+			SiteName:    "127.0.0.1",
+		}
+		if *got != want {
+			t.Errorf("Fetch() = %+v, want %+v", *got, want)
+		}
+	})
+
+	errTests := []struct {
+		name string
+		url  string
+		want error
+	}{
+		{"no title", srv.URL + "/empty", ErrNoMetadata},
+		{"not html", srv.URL + "/json", ErrNotHTML},
+		{"unsupported scheme", "ftp://example.com/file", ErrUnsupportedURL},
+		{"no host", "http:///path", ErrUnsupportedURL},
+	}
+
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := fetcher.Fetch(context.Background(), tt.url); !errors.Is(err, tt.want) {
+				t.Errorf("Fetch(%q) error = %v, want %v", tt.url, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("error status", func(t *testing.T) {
+		if _, err := fetcher.Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+			t.Error("Fetch() error = nil, want an error for a 404")
+		}
+	})
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	padding := "<!--" + strings.Repeat(" ", maxBodySize) + "-->"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/late", serveHTML(`<html><head>`+padding+`<title>Too far in</title></head></html>`))
+	mux.HandleFunc("/early", serveHTML(`<html><head><title>Early</title></head><body>`+padding+`</body></html>`))
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := New(testClient(srv, time.Second))
+
+	if _, err := fetcher.Fetch(context.Background(), srv.URL+"/late"); !errors.Is(err, ErrNoMetadata) {
+		t.Errorf("title past the size limit: error = %v, want %v", err, ErrNoMetadata)
+	}
+
+	got, err := fetcher.Fetch(context.Background(), srv.URL+"/early")
+	if err != nil {
+		t.Fatalf("title within the size limit: error = %v", err)
+	}
+	if got.Title != "Early" {
+		t.Errorf("title within the size limit: Title = %q, want %q", got.Title, "Early")
+	}
+}
+
+func TestFetchRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", serveHTML(`<html><head><title>Landed</title></head></html>`))
+	// /hops/n redirects n more times before reaching /page
+	mux.HandleFunc("/hops/{n}", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscan(r.PathValue("n"), &n)
+		if n <= 1 {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/hops/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/ftp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := New(testClient(srv, time.Second))
+
+	got, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hops/%d", srv.URL, maxRedirects))
+	if err != nil {
+		t.Fatalf("%d redirects: error = %v", maxRedirects, err)
+	}
+	if got.Title != "Landed" {
+		t.Errorf("%d redirects: Title = %q, want %q", maxRedirects, got.Title, "Landed")
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), fmt.Sprintf("%s/hops/%d", srv.URL, maxRedirects+1)); err == nil {
+		t.Errorf("%d redirects: error = nil, want an error", maxRedirects+1)
+	}
+
+	if _, err := fetcher.Fetch(context.Background(), srv.URL+"/ftp"); err == nil {
+		t.Error("redirect to ftp: error = nil, want an error")
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	fetcher := New(testClient(srv, 100*time.Millisecond))
+
+	start := time.Now()
+	if _, err := fetcher.Fetch(context.Background(), srv.URL); err == nil {
+		t.Fatal("Fetch() error = nil, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Fetch() took %v, want it to give up after the timeout", elapsed)
+	}
+}
+
+func TestNewClientBlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(serveHTML(`<html><head><title>Internal</title></head></html>`))
+	defer srv.Close()
+
+	fetcher := New(NewClient(time.Second))
+
+	if _, err := fetcher.Fetch(context.Background(), srv.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch(%q) error = %v, want %v", srv.URL, err, ErrBlockedAddress)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"93.184.216.34", true},
+		{"2606:4700:4700::1111", true},
+		{"::ffff:8.8.8.8", true},
+		{"10.0.0.1", false},
+		{"10.255.255.255", false},
+		{"127.0.0.1", false},
+		{"127.1.2.3", false},
+		{"::1", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:192.168.1.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
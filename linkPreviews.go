@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/entities"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	// previewTTL is how long a fetched preview, or a failed fetch, is reused
+	// before the page is fetched again.
+	previewTTL       = time.Hour * 24
+	previewTimeout   = time.Second * 10
+	previewQueueSize = 256
+	previewWorkers   = 4
+)
+
+// queueLinkPreviews schedules preview fetches for the links in a chirp body.
+// It never blocks the request; links that do not fit in the queue are
+// dropped and picked up the next time they are posted.
+func (cfg *apiConfig) queueLinkPreviews(body string) {
+	for _, link := range entities.URLs(body) {
+		select {
+		case cfg.previewQueue <- link:
+		default:
+			log.Printf("Link preview queue full, dropping %s", link)
+		}
+	}
+}
+
+// runPreviewWorker fetches queued links until the queue is closed.
+func (cfg *apiConfig) runPreviewWorker() {
+	for link := range cfg.previewQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+		if err := cfg.fetchLinkPreview(ctx, link); err != nil {
+			log.Printf("Error saving link preview for %s: %v", link, err)
+		}
+		cancel()
+	}
+}
+
+func (cfg *apiConfig) fetchLinkPreview(ctx context.Context, link string) error {
+	fetchedAt, err := cfg.queries.GetLinkPreviewFetchedAt(ctx, link)
+	if err == nil && time.Since(fetchedAt) < previewTTL {
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// failures are stored too so broken links are not retried on every chirp
+	preview, err := cfg.unfurler.Fetch(ctx, link)
+	if err != nil {
+		return cfg.queries.UpsertLinkPreview(ctx, database.UpsertLinkPreviewParams{
+			Url:    link,
+			Failed: true,
+		})
+	}
+
+	return cfg.queries.UpsertLinkPreview(ctx, database.UpsertLinkPreviewParams{
+		Url:         link,
+		Title:       preview.Title,
+		Description: preview.Description,
+		ImageUrl:    preview.ImageURL,
+		SiteName:    preview.SiteName,
+	})
+}
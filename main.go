@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"david-galdamez/chirp/internal/contentfilter"
 	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/events"
+	"david-galdamez/chirp/internal/mailer"
+	"david-galdamez/chirp/internal/storage"
+	"david-galdamez/chirp/internal/unfurl"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -16,8 +24,6 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
-var invalid_words = []string{"kerfuffle", "sharbert", "fornax"}
-
 func main() {
 	mux := http.NewServeMux()
 
@@ -43,32 +49,176 @@ func main() {
 		log.Fatalf("Secret key not found")
 	}
 
+	// the file only seeds the table: each word is added once, so words an
+	// admin removes stay removed across restarts
+	if path := os.Getenv("BANNED_WORDS_FILE"); path != "" {
+		fileWords, err := contentfilter.LoadFile(path)
+		if err != nil {
+			log.Fatalf("Error loading banned words file: %v", err)
+		}
+
+		for _, word := range fileWords {
+			word = contentfilter.Normalize(word)
+			if !contentfilter.IsWord(word) {
+				log.Printf("Skipping banned word %q: not a single word", word)
+				continue
+			}
+
+			if err := dbQueries.SeedBannedWord(context.Background(), word); err != nil {
+				log.Fatalf("Error seeding banned words: %v", err)
+			}
+		}
+	}
+
+	bannedWords, err := dbQueries.GetBannedWords(context.Background())
+	if err != nil {
+		log.Fatalf("Error loading banned words: %v", err)
+	}
+
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+
+	mediaStorage, err := storage.NewLocal(mediaDir, "/media")
+	if err != nil {
+		log.Fatalf("Error creating media storage: %v", err)
+	}
+
+	restoreWindow, err := durationEnv("CHIRP_RESTORE_WINDOW", time.Hour*24)
+	if err != nil {
+		log.Fatalf("Error reading CHIRP_RESTORE_WINDOW: %v", err)
+	}
+
+	retention, err := durationEnv("CHIRP_RETENTION", time.Hour*24*30)
+	if err != nil {
+		log.Fatalf("Error reading CHIRP_RETENTION: %v", err)
+	}
+
+	// a chirp must never be purged while it can still be restored
+	if retention < restoreWindow {
+		log.Fatalf("CHIRP_RETENTION must not be shorter than CHIRP_RESTORE_WINDOW")
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <no-reply@localhost>"
+	}
+
+	// without an SMTP server, mail is written to a file for local testing
+	var mail mailer.Mailer
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		mail = mailer.NewSMTP(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom)
+	} else {
+		mailFile := os.Getenv("MAIL_FILE")
+		if mailFile == "" {
+			mailFile = "mail.log"
+		}
+		mail = mailer.NewFile(mailFile, mailFrom)
+	}
+
+	hub := events.NewHub(1024)
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
+		db:             db,
 		queries:        dbQueries,
+		filter:         contentfilter.New(bannedWords),
+		hub:            hub,
+		storage:        mediaStorage,
+		unfurler:       unfurl.New(unfurl.NewClient(time.Second * 5)),
+		previewQueue:   make(chan string, previewQueueSize),
+		restoreWindow:  restoreWindow,
+		retention:      retention,
+		mailer:         mail,
 		secretKey:      secretKey,
 		polkaKey:       polkaKey,
+		adminKey:       os.Getenv("ADMIN_KEY"),
 	}
 
 	mux.Handle("/api/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
 
+	go events.ListenPostgres(dbURL, hub, apiCfg.reloadBannedWords)
+
+	for range previewWorkers {
+		go apiCfg.runPreviewWorker()
+	}
+	go apiCfg.runScheduler()
+	go apiCfg.runPurger()
+
+	mux.Handle("GET /media/", http.StripPrefix("/media", mediaStorage))
+
 	mux.HandleFunc("GET /admin/metrics", apiCfg.serveMetrics)
 	mux.HandleFunc("POST /admin/reset", apiCfg.resetMetric)
 
+	mux.HandleFunc("GET /admin/banned-words", apiCfg.getBannedWords)
+	mux.HandleFunc("POST /admin/banned-words", apiCfg.addBannedWord)
+	mux.HandleFunc("DELETE /admin/banned-words/{word}", apiCfg.removeBannedWord)
+
 	mux.HandleFunc("POST /api/users", apiCfg.createUser)
 	mux.HandleFunc("POST /api/login", apiCfg.loginUser)
+	mux.HandleFunc("PATCH /api/users", apiCfg.updateUser)
 	mux.HandleFunc("PUT /api/users", apiCfg.updateUser)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.verifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiCfg.resendVerification)
+
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.updateProfile)
+	mux.HandleFunc("GET /api/users/{userID}", apiCfg.getUserProfile)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.followUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowUser)
+	// followers, following and by-handle/{handle} share one pattern, see
+	// getUserRelation
+	mux.HandleFunc("GET /api/users/{userID}/{rel}", apiCfg.getUserRelation)
+	mux.HandleFunc("GET /api/timeline", apiCfg.getTimeline)
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.readNotifications)
+
+	mux.HandleFunc("GET /api/tags/trending", apiCfg.getTrendingTags)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.getTagChirps)
 
 	mux.HandleFunc("POST /api/refresh", apiCfg.refreshToken)
 	mux.HandleFunc("POST /api/revoke", apiCfg.revokeToken)
 
+	mux.HandleFunc("POST /api/media", apiCfg.uploadMedia)
+
 	mux.HandleFunc("POST /api/chirps", apiCfg.createChirp)
-	mux.HandleFunc("DELETE /api/chirps/{chirpId}", apiCfg.deleteChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.updateChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.restoreChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.rechirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/pin", apiCfg.pinChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/pin", apiCfg.unpinChirp)
+
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.getScheduledChirps)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{draftID}", apiCfg.deleteScheduledChirp)
+
+	mux.HandleFunc("POST /api/drafts", apiCfg.createDraft)
+	mux.HandleFunc("GET /api/drafts", apiCfg.getDrafts)
+	mux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.getDraft)
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.updateDraft)
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.deleteDraft)
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.publishDraft)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiCfg.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiCfg.unlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/bookmark", apiCfg.bookmarkChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.unbookmarkChirp)
+	mux.HandleFunc("GET /api/bookmarks", apiCfg.getBookmarks)
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.chirpyRed)
 
 	mux.HandleFunc("GET /api/chirps", apiCfg.getChirps)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.searchChirps)
+	mux.HandleFunc("GET /api/chirps/stream", apiCfg.streamChirps)
+	mux.HandleFunc("GET /api/ws", apiCfg.chirpsWebSocket)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.getChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.getChirpThread)
 
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "text/plain")
@@ -86,3 +236,23 @@ func main() {
 		log.Printf("Error listening to server: %v", err)
 	}
 }
+
+// durationEnv reads a positive duration such as "24h" from the environment,
+// falling back to def when the variable is unset.
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+
+	return d, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/media"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxChirpMedia is how many media files can be attached to one chirp.
+const maxChirpMedia = 4
+
+func (cfg *apiConfig) newMedia(file database.MediaFile) models.Media {
+	return models.Media{
+		ID:           file.ID,
+		URL:          cfg.storage.URL(file.StorageKey),
+		ThumbnailURL: cfg.storage.URL(file.ThumbnailKey),
+		ContentType:  file.ContentType,
+		Width:        file.Width,
+		Height:       file.Height,
+	}
+}
+
+// deleteMediaFiles removes stored files whose rows are already gone. Failures
+// are only logged since the chirp they belonged to no longer exists.
+func (cfg *apiConfig) deleteMediaFiles(ctx context.Context, files []database.MediaFile) {
+	for _, file := range files {
+		for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+			if err := cfg.storage.Delete(ctx, key); err != nil {
+				log.Printf("Error deleting media %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// uploadMedia stores an image sent as the "file" field of a multipart form.
+// The returned id can then be attached to a chirp through media_ids.
+func (cfg *apiConfig) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	// leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxSize+1<<20)
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte(`{"error": "file too large"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "file is required"}`))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxSize+1))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "error reading file"}`))
+		return
+	}
+
+	if len(data) > media.MaxSize {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"error": "file too large"}`))
+		return
+	}
+
+	img, err := media.Process(data)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedType) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			w.Write([]byte(`{"error": "unsupported media type"}`))
+			return
+		}
+
+		if errors.Is(err, media.ErrTooLarge) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte(`{"error": "image dimensions too large"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	id := uuid.New()
+	mediaFile := database.MediaFile{
+		StorageKey:   id.String() + img.Extension,
+		ThumbnailKey: id.String() + "_thumb" + img.ThumbnailExtension,
+	}
+
+	if err := cfg.storage.Put(r.Context(), mediaFile.StorageKey, bytes.NewReader(data)); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error storing media"}`))
+		return
+	}
+
+	if err := cfg.storage.Put(r.Context(), mediaFile.ThumbnailKey, bytes.NewReader(img.Thumbnail)); err != nil {
+		cfg.deleteMediaFiles(r.Context(), []database.MediaFile{mediaFile})
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error storing media"}`))
+		return
+	}
+
+	created, err := cfg.queries.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		ID:           id,
+		UserID:       userId,
+		ContentType:  img.ContentType,
+		SizeBytes:    int64(len(data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   mediaFile.StorageKey,
+		ThumbnailKey: mediaFile.ThumbnailKey,
+	})
+	if err != nil {
+		cfg.deleteMediaFiles(r.Context(), []database.MediaFile{mediaFile})
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error storing media"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(cfg.newMedia(created))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonRes)
+}
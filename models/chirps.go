@@ -7,9 +7,49 @@ import (
 )
 
 type Chirp struct {
+	ID            uuid.UUID    `json:"id"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	Body          string       `json:"body"`
+	UserId        uuid.UUID    `json:"user_id"`
+	ParentChirpId *uuid.UUID   `json:"parent_chirp_id"`
+	RepostOfId    *uuid.UUID   `json:"repost_of_id"`
+	Visibility    string       `json:"visibility"`
+	ReplyCount    int64        `json:"reply_count"`
+	LikeCount     int64        `json:"like_count"`
+	LikedByMe     *bool        `json:"liked_by_me"`
+	Tags          []string     `json:"tags"`
+	Mentions      []Mention    `json:"mentions"`
+	Media         []Media      `json:"media"`
+	Preview       *LinkPreview `json:"preview"`
+}
+
+type Mention struct {
+	UserId uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+}
+
+type ChirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// ChirpSearchResult is a search hit. Snippet is an HTML fragment: the chirp
+// text is escaped and the matched terms are wrapped in <mark> tags.
+type ChirpSearchResult struct {
+	Chirp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type ChirpRevision struct {
 	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
 	Body      string    `json:"body"`
-	UserId    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpThread struct {
+	Chirp
+	Replies []*ChirpThread `json:"replies"`
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Draft struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Body          string      `json:"body"`
+	ParentChirpId *uuid.UUID  `json:"parent_chirp_id"`
+	MediaIds      []uuid.UUID `json:"media_ids"`
+	PublishAt     *time.Time  `json:"publish_at"`
+	Visibility    string      `json:"visibility"`
+	// PublishError is why the last scheduled publish failed, if it did
+	PublishError *string `json:"publish_error"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Follow struct {
+	UserId     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowsPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor *string  `json:"next_cursor"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	ActorId   uuid.UUID  `json:"actor_id"`
+	ChirpId   *uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+type NotificationsPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    *string        `json:"next_cursor"`
+}
@@ -7,11 +7,16 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        *string   `json:"token"`
-	RefreshToken *string   `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red'`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	Handle        *string    `json:"handle"`
+	DisplayName   string     `json:"display_name"`
+	Bio           string     `json:"bio"`
+	AvatarMediaId *uuid.UUID `json:"avatar_media_id"`
+	Token         *string    `json:"token"`
+	RefreshToken  *string    `json:"refresh_token"`
+	IsChirpyRed   bool       `json:"is_chirpy_red"`
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/pagination"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	notificationMention = "mention"
+	notificationReply   = "reply"
+	notificationLike    = "like"
+	notificationRechirp = "rechirp"
+	notificationFollow  = "follow"
+)
+
+// notify records a notification for recipient. Users are never notified of
+// their own actions.
+func notify(ctx context.Context, q *database.Queries, recipient, actor uuid.UUID, notificationType string, chirpId uuid.NullUUID) error {
+	if recipient == actor {
+		return nil
+	}
+
+	return q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		ActorID: actor,
+		Type:    notificationType,
+		ChirpID: chirpId,
+	})
+}
+
+func (cfg *apiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	unreadOnly := false
+	if raw := r.URL.Query().Get("unread"); raw != "" {
+		unreadOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "unread must be true or false"}`))
+			return
+		}
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid limit"}`))
+		return
+	}
+
+	cursorCreatedAt, cursorId, err := pagination.ParseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid cursor"}`))
+		return
+	}
+
+	rows, err := cfg.queries.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:          userId,
+		UnreadOnly:      unreadOnly,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorId,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	page := models.NotificationsPage{Notifications: []models.Notification{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor := pagination.EncodeCursor(last.CreatedAt, last.ID)
+		page.NextCursor = &nextCursor
+	}
+
+	for _, row := range rows {
+		notification := models.Notification{
+			ID:        row.ID,
+			Type:      row.Type,
+			ActorId:   row.ActorID,
+			CreatedAt: row.CreatedAt,
+		}
+
+		if row.ChirpID.Valid {
+			notification.ChirpId = &row.ChirpID.UUID
+		}
+
+		if row.ReadAt.Valid {
+			notification.ReadAt = &row.ReadAt.Time
+		}
+
+		page.Notifications = append(page.Notifications, notification)
+	}
+
+	jsonRes, err := json.Marshal(page)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+type ReadNotificationsRequest struct {
+	Ids []uuid.UUID `json:"ids"`
+}
+
+// readNotifications marks the given notifications as read, or all of the
+// user's notifications when no ids are sent.
+func (cfg *apiConfig) readNotifications(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	request := ReadNotificationsRequest{}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Bad request"}`))
+		return
+	}
+
+	if len(request.Ids) == 0 {
+		err = cfg.queries.MarkAllNotificationsRead(r.Context(), userId)
+	} else {
+		err = cfg.queries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: userId,
+			Ids:    request.Ids,
+		})
+	}
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
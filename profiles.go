@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/entities"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// pinChirp pins one of the user's own chirps to their profile, replacing
+// any chirp pinned before.
+func (cfg *apiConfig) pinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	chirp, err := cfg.queries.GetChirp(r.Context(), chirpId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "chirp not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if chirp.UserID != userId {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": "chirp does not belongs to user"}`))
+		return
+	}
+
+	if err := cfg.queries.PinChirp(r.Context(), database.PinChirpParams{
+		ID:      userId,
+		ChirpID: chirp.ID,
+	}); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unpinChirp clears the pin if it still points at the given chirp.
+func (cfg *apiConfig) unpinChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	if err := cfg.queries.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		ID:      userId,
+		ChirpID: chirpId,
+	}); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// userProfile builds the public profile of a user as seen by viewer.
+// Missing users are reported as sql.ErrNoRows.
+func (cfg *apiConfig) userProfile(ctx context.Context, userId uuid.UUID, viewer uuid.NullUUID) (models.Profile, error) {
+	profileDb, err := cfg.queries.GetUserProfile(ctx, database.GetUserProfileParams{
+		ID:       userId,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		return models.Profile{}, err
+	}
+
+	profile := models.Profile{
+		ID:             profileDb.ID,
+		CreatedAt:      profileDb.CreatedAt,
+		DisplayName:    profileDb.DisplayName,
+		Bio:            profileDb.Bio,
+		IsChirpyRed:    profileDb.IsChirpyRed,
+		ChirpCount:     profileDb.ChirpCount,
+		FollowerCount:  profileDb.FollowerCount,
+		FollowingCount: profileDb.FollowingCount,
+	}
+
+	if profileDb.Handle.Valid {
+		profile.Handle = &profileDb.Handle.String
+	}
+
+	if profileDb.AvatarMediaID.Valid {
+		avatar, err := cfg.queries.GetMediaFile(ctx, profileDb.AvatarMediaID.UUID)
+		if err != nil {
+			return models.Profile{}, err
+		}
+
+		media := cfg.newMedia(avatar)
+		profile.Avatar = &media
+	}
+
+	// a pinned chirp that is deleted or hidden from the viewer is left out
+	if profileDb.PinnedChirpID.Valid {
+		pinned, err := cfg.getVisibleChirp(ctx, profileDb.PinnedChirpID.UUID, viewer)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return models.Profile{}, err
+		}
+
+		if err == nil {
+			chirps, err := cfg.toChirpModels(ctx, []database.Chirp{pinned}, viewer)
+			if err != nil {
+				return models.Profile{}, err
+			}
+
+			profile.PinnedChirp = &chirps[0]
+		}
+	}
+
+	return profile, nil
+}
+
+func (cfg *apiConfig) writeUserProfile(w http.ResponseWriter, r *http.Request, userId uuid.UUID) {
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	profile, err := cfg.userProfile(r.Context(), userId, viewer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "user not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(profile)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getUserProfile(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from user id"}`))
+		return
+	}
+
+	cfg.writeUserProfile(w, r, userId)
+}
+
+// getUserRelation serves GET /api/users/{userID}/{rel}. net/http rejects
+// /api/users/by-handle/{handle} next to /api/users/{userID}/followers as
+// conflicting patterns, so handle lookups are dispatched from here.
+func (cfg *apiConfig) getUserRelation(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("userID") == "by-handle" {
+		r.SetPathValue("handle", r.PathValue("rel"))
+		cfg.getUserProfileByHandle(w, r)
+		return
+	}
+
+	switch r.PathValue("rel") {
+	case "followers":
+		cfg.getFollowers(w, r)
+	case "following":
+		cfg.getFollowing(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (cfg *apiConfig) getUserProfileByHandle(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+	if !entities.ValidHandle(handle) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "user not found"}`))
+		return
+	}
+
+	userId, err := cfg.queries.GetUserIdByHandle(r.Context(), sql.NullString{String: strings.ToLower(handle), Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "user not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	cfg.writeUserProfile(w, r, userId)
+}
+
+// ProfileRequest holds the profile fields to change; omitted fields are
+// left as they are. An empty avatar_media_id removes the avatar.
+type ProfileRequest struct {
+	Handle        *string `json:"handle"`
+	DisplayName   *string `json:"display_name"`
+	Bio           *string `json:"bio"`
+	AvatarMediaId *string `json:"avatar_media_id"`
+}
+
+func (cfg *apiConfig) updateProfile(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	request := ProfileRequest{}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Bad request"}`))
+		return
+	}
+
+	params := database.UpdateUserProfileParams{ID: userId}
+
+	if request.Handle != nil {
+		if !entities.ValidHandle(*request.Handle) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "handle must be 1-30 letters, digits or underscores"}`))
+			return
+		}
+
+		params.Handle = sql.NullString{String: strings.ToLower(*request.Handle), Valid: true}
+	}
+
+	if request.DisplayName != nil {
+		displayName := strings.TrimSpace(*request.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "display name is too long"}`))
+			return
+		}
+
+		params.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+
+	if request.Bio != nil {
+		bio := strings.TrimSpace(*request.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "bio is too long"}`))
+			return
+		}
+
+		params.Bio = sql.NullString{String: bio, Valid: true}
+	}
+
+	if request.AvatarMediaId != nil {
+		params.SetAvatar = true
+
+		if *request.AvatarMediaId != "" {
+			avatarId, err := uuid.Parse(*request.AvatarMediaId)
+			if err != nil {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "bad format from avatar media id"}`))
+				return
+			}
+
+			// only the user's own uploads not already on a chirp can become their avatar
+			if _, err := cfg.queries.GetAvatarCandidate(r.Context(), database.GetAvatarCandidateParams{
+				ID:     avatarId,
+				UserID: userId,
+			}); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					w.Header().Add("Content-Type", "application/json")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error": "invalid avatar media id"}`))
+					return
+				}
+
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error": "Internal server error"}`))
+				return
+			}
+
+			params.AvatarMediaID = uuid.NullUUID{UUID: avatarId, Valid: true}
+		}
+	}
+
+	userDb, err := cfg.queries.UpdateUserProfile(r.Context(), params)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "handle already taken"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(newUser(userDb))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
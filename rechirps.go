@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type RechirpRequest struct {
+	Body string `json:"body"`
+}
+
+// rechirp reposts a chirp for the authenticated user. An empty body makes a
+// plain rechirp, which is idempotent per user; a body makes a quote chirp.
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	request := RechirpRequest{}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "Bad request"}`))
+		return
+	}
+
+	body, err := cfg.cleanChirpBody(request.Body)
+	if err != nil {
+		errRes := ErrorResponse{Error: err.Error()}
+		data, _ := json.Marshal(errRes)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write(data)
+		return
+	}
+
+	// a quote chirp is new content, a plain rechirp is not
+	if body != "" && !cfg.requireVerifiedEmail(w, r, userId) {
+		return
+	}
+
+	original, err := cfg.getVisibleChirp(r.Context(), chirpId, uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "chirp not found"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	// rechirping a plain rechirp points at the chirp it reposted
+	if original.RepostOfID.Valid && original.Body == "" {
+		original, err = cfg.queries.GetChirp(r.Context(), original.RepostOfID.UUID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": "chirp not found"}`))
+				return
+			}
+
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+	}
+
+	if original.Visibility != visibilityPublic {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": "only public chirps can be rechirped"}`))
+		return
+	}
+
+	status := http.StatusCreated
+	repostOfId := uuid.NullUUID{UUID: original.ID, Valid: true}
+
+	var chirpDb database.Chirp
+	if body == "" {
+		chirpDb, err = cfg.queries.GetPlainRechirp(r.Context(), database.GetPlainRechirpParams{
+			UserID:     userId,
+			RepostOfID: repostOfId,
+		})
+		if err == nil {
+			status = http.StatusOK
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+	}
+
+	if status == http.StatusCreated {
+		chirpDb, err = cfg.insertRechirp(r.Context(), database.CreateChirpParams{
+			Body:       body,
+			UserID:     userId,
+			RepostOfID: repostOfId,
+			Visibility: visibilityPublic,
+		}, original.UserID)
+
+		// a concurrent request made the same plain rechirp first
+		var pqErr *pq.Error
+		if body == "" && errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			status = http.StatusOK
+			chirpDb, err = cfg.queries.GetPlainRechirp(r.Context(), database.GetPlainRechirpParams{
+				UserID:     userId,
+				RepostOfID: repostOfId,
+			})
+		}
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Error creating chirp"}`))
+			return
+		}
+
+		if status == http.StatusCreated {
+			cfg.queueLinkPreviews(chirpDb.Body)
+		}
+	}
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if status == http.StatusCreated {
+		cfg.broadcastChirp(r.Context(), chirps[0])
+	}
+
+	jsonRes, err := json.Marshal(chirps[0])
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonRes)
+}
+
+// insertRechirp creates the rechirp, its entities and the notification for
+// the original author in one transaction.
+func (cfg *apiConfig) insertRechirp(ctx context.Context, params database.CreateChirpParams, originalUserId uuid.UUID) (database.Chirp, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+
+	chirp, _, err := insertChirp(ctx, qtx, params, uuid.NullUUID{}, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := notify(ctx, qtx, originalUserId, chirp.UserID, notificationRechirp, uuid.NullUUID{UUID: chirp.ID, Valid: true}); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.Chirp{}, err
+	}
+
+	return chirp, nil
+}
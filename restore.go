@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// purgeInterval is how often soft-deleted chirps past the retention period
+// are removed for good.
+const purgeInterval = time.Hour
+
+// orphanedMediaAge is how long an upload may sit unattached before the purge
+// job removes it, unless a draft or a profile still uses it.
+const orphanedMediaAge = 24 * time.Hour
+
+// restoreChirp undoes a delete made within the restore window. Plain
+// rechirps that were deleted along with the chirp come back with it.
+func (cfg *apiConfig) restoreChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from chirp id"}`))
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+
+	// deleted chirps are only visible to their author
+	deleted, err := qtx.GetDeletedChirp(r.Context(), chirpId)
+	if (err == nil && deleted.UserID != userId) || errors.Is(err, sql.ErrNoRows) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "deleted chirp not found"}`))
+		return
+	}
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	chirpDb, err := qtx.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:     chirpId,
+		Cutoff: time.Now().UTC().Add(-cfg.restoreWindow),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"error": "restore window has expired"}`))
+			return
+		}
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "chirp was rechirped again since it was deleted"}`))
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if err := qtx.RestorePlainRechirps(r.Context(), database.RestorePlainRechirpsParams{
+		ChirpID:   chirpId,
+		DeletedAt: deleted.DeletedAt.Time,
+	}); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	chirps, err := cfg.toChirpModels(r.Context(), []database.Chirp{chirpDb}, uuid.NullUUID{UUID: userId, Valid: true})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	cfg.broadcastChirp(r.Context(), chirps[0])
+
+	jsonRes, err := json.Marshal(chirps[0])
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+// runPurger hard-deletes chirps that were soft-deleted longer than the
+// retention period ago, along with media nothing refers to any more.
+func (cfg *apiConfig) runPurger() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := cfg.purgeDeletedChirps(context.Background()); err != nil {
+			log.Printf("Error purging deleted chirps: %v", err)
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+	now := time.Now().UTC()
+	retentionCutoff := now.Add(-cfg.retention)
+
+	// media rows are removed first as the chirp delete would only detach them
+	mediaFiles, err := qtx.DeletePurgedMediaFiles(ctx, retentionCutoff)
+	if err != nil {
+		return err
+	}
+
+	if err := qtx.PurgeDeletedChirps(ctx, retentionCutoff); err != nil {
+		return err
+	}
+
+	orphaned, err := qtx.DeleteOrphanedMediaFiles(ctx, now.Add(-orphanedMediaAge))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.deleteMediaFiles(ctx, append(mediaFiles, orphaned...))
+
+	return nil
+}
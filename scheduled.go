@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// schedulerInterval is how often due scheduled chirps are looked for.
+const schedulerInterval = time.Second * 15
+
+// maxPublishAttempts is how often a scheduled chirp is tried before it is
+// taken off the schedule.
+const maxPublishAttempts = 5
+
+func newDraft(draft database.Draft) models.Draft {
+	newDraft := models.Draft{
+		ID:         draft.ID,
+		CreatedAt:  draft.CreatedAt,
+		UpdatedAt:  draft.UpdatedAt,
+		Body:       draft.Body,
+		MediaIds:   draft.MediaIds,
+		Visibility: draft.Visibility,
+	}
+
+	if newDraft.MediaIds == nil {
+		newDraft.MediaIds = []uuid.UUID{}
+	}
+
+	if draft.ParentChirpID.Valid {
+		newDraft.ParentChirpId = &draft.ParentChirpID.UUID
+	}
+
+	if draft.PublishAt.Valid {
+		newDraft.PublishAt = &draft.PublishAt.Time
+	}
+
+	if draft.PublishError.Valid {
+		newDraft.PublishError = &draft.PublishError.String
+	}
+
+	return newDraft
+}
+
+// scheduleChirp stores an already validated chirp as a draft for the
+// scheduler to publish at params.PublishAt.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, r *http.Request, params database.CreateDraftParams) {
+	if len(params.MediaIds) > 0 {
+		attachable, err := cfg.queries.CountAttachableMediaFiles(r.Context(), database.CountAttachableMediaFilesParams{
+			MediaIds: params.MediaIds,
+			UserID:   params.UserID,
+		})
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "Internal server error"}`))
+			return
+		}
+
+		if attachable != int64(len(params.MediaIds)) {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid media ids"}`))
+			return
+		}
+	}
+
+	draft, err := cfg.queries.CreateDraft(r.Context(), params)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error scheduling chirp"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(newDraft(draft))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftsDb, err := cfg.queries.GetScheduledDrafts(r.Context(), userId)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	drafts := []models.Draft{}
+	for _, draft := range draftsDb {
+		drafts = append(drafts, newDraft(draft))
+	}
+
+	jsonRes, err := json.Marshal(drafts)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) deleteScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	userId, err := auth.ValidateJWT(token, cfg.secretKey)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	draftId, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "bad format from scheduled chirp id"}`))
+		return
+	}
+
+	// someone else's scheduled chirp looks the same as a missing one
+	deleted, err := cfg.queries.DeleteScheduledDraft(r.Context(), database.DeleteScheduledDraftParams{
+		ID:     draftId,
+		UserID: userId,
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	if deleted == 0 {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "scheduled chirp not found"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runScheduler publishes scheduled chirps as they come due. It is safe to
+// run on every instance sharing the database.
+func (cfg *apiConfig) runScheduler() {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			handled, err := cfg.publishDueDraft(context.Background())
+			if err != nil {
+				log.Printf("Error publishing scheduled chirp: %v", err)
+				break
+			}
+
+			if !handled {
+				break
+			}
+		}
+	}
+}
+
+// publishDueDraft turns one due scheduled chirp into a real chirp. The draft
+// row stays locked until it is deleted in the same transaction, so another
+// instance can never publish it as well. It reports whether a due draft was
+// taken care of, published or not; a draft that fails is pushed back so the
+// ones due after it still go out.
+func (cfg *apiConfig) publishDueDraft(ctx context.Context) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.queries.WithTx(tx)
+
+	draft, err := qtx.GetDueDraft(ctx, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	chirpDb, published, publishErr := cfg.publishScheduledDraft(ctx, qtx, draft)
+	if publishErr == nil {
+		publishErr = tx.Commit()
+	}
+	if publishErr != nil {
+		// the failed transaction has to go before the draft can be updated
+		tx.Rollback()
+		log.Printf("Error publishing scheduled chirp %s: %v", draft.ID, publishErr)
+
+		if err := cfg.queries.FailScheduledDraft(ctx, database.FailScheduledDraftParams{
+			ID:           draft.ID,
+			PublishError: publishErr.Error(),
+			MaxAttempts:  maxPublishAttempts,
+		}); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	if !published {
+		return true, nil
+	}
+
+	chirps, err := cfg.toChirpModels(ctx, []database.Chirp{chirpDb}, uuid.NullUUID{})
+	if err != nil {
+		return true, err
+	}
+
+	cfg.broadcastChirp(ctx, chirps[0])
+	cfg.queueLinkPreviews(chirpDb.Body)
+
+	return true, nil
+}
+
+// publishScheduledDraft does the work of publishDueDraft inside its
+// transaction. It reports false when the draft was taken off the schedule
+// instead of published.
+func (cfg *apiConfig) publishScheduledDraft(ctx context.Context, qtx *database.Queries, draft database.Draft) (database.Chirp, bool, error) {
+	// the author's email may have changed since the chirp was scheduled;
+	// it then stays behind as a plain draft
+	author, err := qtx.GetUserById(ctx, draft.UserID)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+
+	if !author.EmailVerified {
+		return database.Chirp{}, false, qtx.UnscheduleDraft(ctx, draft.ID)
+	}
+
+	// a parent deleted since the chirp was scheduled turns it into a
+	// top-level chirp
+	parentUserId := uuid.NullUUID{}
+	if draft.ParentChirpID.Valid {
+		parent, err := qtx.GetChirp(ctx, draft.ParentChirpID.UUID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return database.Chirp{}, false, err
+		}
+
+		if err == nil {
+			parentUserId = uuid.NullUUID{UUID: parent.UserID, Valid: true}
+		} else {
+			draft.ParentChirpID = uuid.NullUUID{}
+		}
+	}
+
+	// the body was checked when it was scheduled and is only filtered again
+	// for words banned since; media used elsewhere in the meantime is
+	// skipped rather than failing
+	chirpDb, _, err := insertChirp(ctx, qtx, database.CreateChirpParams{
+		Body:          cfg.filter.Clean(draft.Body),
+		UserID:        draft.UserID,
+		ParentChirpID: draft.ParentChirpID,
+		Visibility:    draft.Visibility,
+	}, parentUserId, draft.MediaIds)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+
+	if err := qtx.DeleteDraft(ctx, draft.ID); err != nil {
+		return database.Chirp{}, false, err
+	}
+
+	return chirpDb, true, nil
+}
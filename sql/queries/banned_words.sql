@@ -0,0 +1,22 @@
+-- name: GetBannedWords :many
+SELECT word FROM banned_words ORDER BY word ASC;
+
+-- name: CreateBannedWord :exec
+INSERT INTO banned_words (word)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBannedWord :exec
+DELETE FROM banned_words WHERE word = $1;
+
+-- name: SeedBannedWord :exec
+-- adds the word unless it was seeded before
+WITH seeded AS (
+    INSERT INTO banned_word_seeds (word)
+    VALUES ($1)
+    ON CONFLICT DO NOTHING
+    RETURNING word
+)
+INSERT INTO banned_words (word)
+SELECT word FROM seeded
+ON CONFLICT DO NOTHING;
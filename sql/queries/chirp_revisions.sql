@@ -0,0 +1,7 @@
+-- name: CreateChirpRevision :one
+INSERT INTO chirp_revisions (chirp_id, body)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions WHERE chirp_id = $1 ORDER BY created_at DESC;
@@ -1,22 +1,201 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+INSERT INTO chirps(id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility)
 VALUES(
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
--- name: GetChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+-- name: GetChirp :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL;
 
--- name: GetChirpsById :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;
 
--- name: GetChirp :one
-SELECT * FROM chirps WHERE id = $1;
+-- name: GetDeletedChirp :one
+SELECT * FROM chirps WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: UpdateChirp :one
+UPDATE chirps SET body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING *;
+
+-- name: SoftDeleteChirp :exec
+-- plain rechirps of the chirp go with it and share its deleted_at, so they
+-- can be restored together
+UPDATE chirps SET deleted_at = NOW()
+WHERE (id = $1 OR (repost_of_id = $1 AND body = ''))
+    AND deleted_at IS NULL;
+
+-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = sqlc.arg('id')
+    AND deleted_at > sqlc.arg('cutoff')::timestamp
+RETURNING *;
+
+-- name: RestorePlainRechirps :exec
+-- A user who rechirped again in the meantime keeps the newer rechirp and
+-- the old one stays deleted.
+UPDATE chirps SET deleted_at = NULL
+WHERE repost_of_id = sqlc.arg('chirp_id')::uuid AND body = '' AND deleted_at = sqlc.arg('deleted_at')::timestamp
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps live
+        WHERE live.user_id = chirps.user_id
+            AND live.repost_of_id = chirps.repost_of_id
+            AND live.body = ''
+            AND live.deleted_at IS NULL
+    );
+
+-- name: PurgeDeletedChirps :exec
+-- Plain rechirps go with the chirp they repost; quotes only lose the link.
+DELETE FROM chirps
+WHERE deleted_at < sqlc.arg('cutoff')::timestamp
+    OR (body = '' AND repost_of_id IN (
+        SELECT id FROM chirps
+        WHERE deleted_at < sqlc.arg('cutoff')::timestamp
+    ));
+
+-- name: GetChirpsPage :many
+-- like_count is the only numeric sort key; the timestamp keys share the
+-- other CASE so each keyset comparison stays on a single type.
+SELECT sqlc.embed(chirps), like_counts.like_count FROM chirps
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS like_count FROM likes WHERE likes.chirp_id = chirps.id
+) like_counts
+WHERE chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+    AND (sqlc.narg('cursor_id')::uuid IS NULL
+        OR (sqlc.arg('sort_key')::text = 'like_count' AND sqlc.arg('sort_desc')::boolean AND (like_counts.like_count, chirps.id) < (sqlc.narg('cursor_count')::bigint, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text = 'like_count' AND NOT sqlc.arg('sort_desc')::boolean AND (like_counts.like_count, chirps.id) > (sqlc.narg('cursor_count')::bigint, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text <> 'like_count' AND sqlc.arg('sort_desc')::boolean AND (CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) < (sqlc.narg('cursor_key')::timestamp, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text <> 'like_count' AND NOT sqlc.arg('sort_desc')::boolean AND (CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) > (sqlc.narg('cursor_key')::timestamp, sqlc.narg('cursor_id')::uuid)))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_desc')::boolean AND sqlc.arg('sort_key')::text = 'like_count' THEN like_counts.like_count END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::boolean AND sqlc.arg('sort_key')::text <> 'like_count' THEN CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::boolean THEN chirps.id END DESC,
+    CASE WHEN sqlc.arg('sort_key')::text = 'like_count' THEN like_counts.like_count END ASC,
+    CASE WHEN sqlc.arg('sort_key')::text <> 'like_count' THEN CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END END ASC,
+    chirps.id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetChirpsByIdPage :many
+-- like_count is the only numeric sort key; the timestamp keys share the
+-- other CASE so each keyset comparison stays on a single type.
+SELECT sqlc.embed(chirps), like_counts.like_count FROM chirps
+CROSS JOIN LATERAL (
+    SELECT COUNT(*) AS like_count FROM likes WHERE likes.chirp_id = chirps.id
+) like_counts
+WHERE chirps.user_id = sqlc.arg('user_id')
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+    AND (sqlc.narg('cursor_id')::uuid IS NULL
+        OR (sqlc.arg('sort_key')::text = 'like_count' AND sqlc.arg('sort_desc')::boolean AND (like_counts.like_count, chirps.id) < (sqlc.narg('cursor_count')::bigint, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text = 'like_count' AND NOT sqlc.arg('sort_desc')::boolean AND (like_counts.like_count, chirps.id) > (sqlc.narg('cursor_count')::bigint, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text <> 'like_count' AND sqlc.arg('sort_desc')::boolean AND (CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) < (sqlc.narg('cursor_key')::timestamp, sqlc.narg('cursor_id')::uuid))
+        OR (sqlc.arg('sort_key')::text <> 'like_count' AND NOT sqlc.arg('sort_desc')::boolean AND (CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END, chirps.id) > (sqlc.narg('cursor_key')::timestamp, sqlc.narg('cursor_id')::uuid)))
+ORDER BY
+    CASE WHEN sqlc.arg('sort_desc')::boolean AND sqlc.arg('sort_key')::text = 'like_count' THEN like_counts.like_count END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::boolean AND sqlc.arg('sort_key')::text <> 'like_count' THEN CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END END DESC,
+    CASE WHEN sqlc.arg('sort_desc')::boolean THEN chirps.id END DESC,
+    CASE WHEN sqlc.arg('sort_key')::text = 'like_count' THEN like_counts.like_count END ASC,
+    CASE WHEN sqlc.arg('sort_key')::text <> 'like_count' THEN CASE WHEN sqlc.arg('sort_key')::text = 'updated_at' THEN updated_at ELSE created_at END END ASC,
+    chirps.id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: SearchChirps :many
+-- matches in snippet are wrapped in the control characters chr(2) and chr(3),
+-- which are stripped from the body first, so the caller can escape the body
+-- before turning them into markup
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, repost_of_id, visibility,
+    ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank,
+    ts_headline('english', translate(body, chr(2) || chr(3), ''), websearch_to_tsquery('english', sqlc.arg('query')),
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3))::text AS snippet
+FROM chirps
+WHERE to_tsvector('english', body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+ORDER BY rank DESC, created_at DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetReplyCounts :many
+SELECT parent_chirp_id::uuid AS chirp_id, COUNT(*) AS reply_count
+FROM chirps
+WHERE parent_chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+    AND deleted_at IS NULL
+GROUP BY parent_chirp_id;
+
+-- name: GetChirpThread :many
+-- the thread is rooted at the oldest ancestor that is not deleted
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_chirp_id FROM chirps WHERE chirps.id = sqlc.arg('id') AND deleted_at IS NULL
+    UNION ALL
+    SELECT c.id, c.parent_chirp_id FROM chirps c JOIN ancestors a ON c.id = a.parent_chirp_id
+    WHERE c.deleted_at IS NULL
+), thread AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, 0 AS depth
+    FROM chirps JOIN ancestors a ON chirps.id = a.id
+    WHERE NOT EXISTS (SELECT 1 FROM ancestors p WHERE p.id = a.parent_chirp_id)
+        AND (chirps.visibility = 'public'
+            OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+            OR (chirps.visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM follows
+                WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_chirp_id, chirps.repost_of_id, chirps.visibility, t.depth + 1
+    FROM chirps JOIN thread t ON chirps.parent_chirp_id = t.id
+    WHERE chirps.deleted_at IS NULL
+        AND (chirps.visibility = 'public'
+            OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+            OR (chirps.visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM follows
+                WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+)
+SELECT thread.id, thread.created_at, thread.updated_at, thread.body, thread.user_id, thread.parent_chirp_id, thread.repost_of_id, thread.visibility, thread.depth::int AS depth
+FROM thread
+ORDER BY depth ASC, created_at ASC;
+
+-- name: GetTimeline :many
+SELECT chirps.* FROM chirps
+JOIN follows ON chirps.user_id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+    AND chirps.deleted_at IS NULL
+    AND chirps.visibility <> 'private'
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetPlainRechirp :one
+SELECT * FROM chirps WHERE user_id = $1 AND repost_of_id = $2 AND body = '' AND deleted_at IS NULL;
 
--- name: DeleteChirp :exec
-DELETE FROM chirps WHERE id = $1;
+-- name: GetChirpsByTag :many
+SELECT chirps.* FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE tags.name = sqlc.arg('tag')
+    AND chirps.deleted_at IS NULL
+    AND (chirps.visibility = 'public'
+        OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+        OR (chirps.visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
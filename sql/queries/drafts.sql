@@ -0,0 +1,61 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (user_id, body, parent_chirp_id, media_ids, publish_at, visibility)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetScheduledDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1 AND publish_at IS NOT NULL
+ORDER BY publish_at ASC, id ASC;
+
+-- name: DeleteScheduledDraft :execrows
+DELETE FROM drafts
+WHERE id = $1 AND user_id = $2 AND publish_at IS NOT NULL;
+
+-- name: GetDueDraft :one
+-- SKIP LOCKED lets several instances publish concurrently without taking
+-- the same draft twice.
+SELECT * FROM drafts
+WHERE publish_at <= sqlc.arg('now')::timestamp
+ORDER BY publish_at ASC
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: DeleteDraft :exec
+DELETE FROM drafts WHERE id = $1;
+
+-- name: FailScheduledDraft :exec
+-- The draft is retried a few times with a growing delay, then taken off the
+-- schedule and kept as a plain draft.
+UPDATE drafts
+SET publish_attempts = publish_attempts + 1,
+    publish_error = sqlc.arg('publish_error')::text,
+    publish_at = CASE WHEN publish_attempts + 1 < sqlc.arg('max_attempts')::int
+        THEN NOW() + (publish_attempts + 1) * INTERVAL '1 minute' END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND publish_at IS NOT NULL;
+
+-- name: UnscheduleDraft :exec
+UPDATE drafts SET publish_at = NULL, updated_at = NOW() WHERE id = $1;
+
+-- name: GetDrafts :many
+SELECT * FROM drafts
+WHERE user_id = $1
+ORDER BY updated_at DESC, id DESC;
+
+-- name: GetDraft :one
+SELECT * FROM drafts WHERE id = $1 AND user_id = $2;
+
+-- name: GetDraftForUpdate :one
+SELECT * FROM drafts WHERE id = $1 AND user_id = $2
+FOR UPDATE;
+
+-- name: UpdateDraft :one
+UPDATE drafts
+SET body = $3, parent_chirp_id = $4, media_ids = $5, publish_at = $6, visibility = $7,
+    publish_attempts = 0, publish_error = NULL, updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteUserDraft :execrows
+DELETE FROM drafts WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,21 @@
+-- name: CreateVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+VALUES (
+    sqlc.arg('token_hash'),
+    sqlc.arg('user_id'),
+    sqlc.arg('email'),
+    NOW() + sqlc.arg('ttl_seconds')::int * INTERVAL '1 second'
+);
+
+-- name: DeleteUserVerificationTokens :exec
+DELETE FROM email_verification_tokens WHERE user_id = $1;
+
+-- name: ConsumeVerificationToken :one
+DELETE FROM email_verification_tokens
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: MarkEmailVerified :execrows
+-- a token sent to an address the user has since changed no longer counts
+UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND email = $2;
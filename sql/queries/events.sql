@@ -0,0 +1,5 @@
+-- name: NextEventId :one
+SELECT nextval('chirp_events_id_seq')::bigint AS id;
+
+-- name: NotifyEvent :exec
+SELECT pg_notify('chirp_events', sqlc.arg('payload')::text);
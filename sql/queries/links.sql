@@ -0,0 +1,35 @@
+-- name: AddChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links WHERE chirp_id = $1;
+
+-- name: GetLinkPreviewFetchedAt :one
+SELECT fetched_at FROM link_previews WHERE url = $1;
+
+-- name: UpsertLinkPreview :exec
+INSERT INTO link_previews (url, title, description, image_url, site_name, failed, fetched_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (url) DO UPDATE SET
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    image_url = EXCLUDED.image_url,
+    site_name = EXCLUDED.site_name,
+    failed = EXCLUDED.failed,
+    fetched_at = EXCLUDED.fetched_at;
+
+-- name: GetChirpLinkPreviews :many
+SELECT DISTINCT ON (chirp_links.chirp_id)
+    chirp_links.chirp_id,
+    link_previews.url,
+    link_previews.title,
+    link_previews.description,
+    link_previews.image_url,
+    link_previews.site_name
+FROM chirp_links
+JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND NOT link_previews.failed
+ORDER BY chirp_links.chirp_id, chirp_links.position ASC;
@@ -0,0 +1,52 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, user_id, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: AttachMediaFiles :execrows
+UPDATE media_files
+SET chirp_id = sqlc.arg('chirp_id')::uuid,
+    position = array_position(sqlc.arg('media_ids')::uuid[], id)
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id);
+
+-- name: GetAvatarCandidate :one
+-- An avatar can't also be attached to a chirp, or purging the chirp would
+-- take the avatar file with it.
+SELECT * FROM media_files WHERE id = $1 AND user_id = $2 AND chirp_id IS NULL;
+
+-- name: GetMediaFile :one
+SELECT * FROM media_files WHERE id = $1;
+
+-- name: GetChirpMediaFiles :many
+SELECT * FROM media_files
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position ASC;
+
+-- name: CountAttachableMediaFiles :one
+SELECT COUNT(*) FROM media_files
+WHERE id = ANY(sqlc.arg('media_ids')::uuid[])
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NULL
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id);
+
+-- name: DeletePurgedMediaFiles :many
+DELETE FROM media_files
+WHERE chirp_id IN (
+    SELECT id FROM chirps
+    WHERE deleted_at < sqlc.arg('cutoff')::timestamp
+)
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+RETURNING *;
+
+-- name: DeleteOrphanedMediaFiles :many
+-- Uploads that never made it onto a chirp, once no draft or profile refers
+-- to them any more.
+DELETE FROM media_files
+WHERE chirp_id IS NULL
+AND created_at < sqlc.arg('cutoff')::timestamp
+AND NOT EXISTS (SELECT 1 FROM users WHERE users.avatar_media_id = media_files.id)
+AND NOT EXISTS (SELECT 1 FROM drafts WHERE media_files.id = ANY(drafts.media_ids))
+RETURNING *;
@@ -0,0 +1,33 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (user_id, actor_id, type, chirp_id)
+VALUES ($1, $2, $3, $4)
+-- either the per-chirp or the chirpless dedupe index may conflict
+ON CONFLICT DO NOTHING;
+
+-- name: GetNotifications :many
+-- notifications about chirps that were deleted or that the user can no
+-- longer see are left out
+SELECT * FROM notifications
+WHERE notifications.user_id = sqlc.arg('user_id')
+    AND (notifications.chirp_id IS NULL OR EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = notifications.chirp_id
+            AND chirps.deleted_at IS NULL
+            AND (chirps.visibility = 'public'
+                OR chirps.user_id = notifications.user_id
+                OR (chirps.visibility = 'followers' AND EXISTS (
+                    SELECT 1 FROM follows
+                    WHERE follows.follower_id = notifications.user_id AND follows.followee_id = chirps.user_id)))))
+    AND (NOT sqlc.arg('unread_only')::boolean OR notifications.read_at IS NULL)
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (notifications.created_at, notifications.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL AND id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND read_at IS NULL;
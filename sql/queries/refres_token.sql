@@ -4,7 +4,11 @@ VALUES ($1, $2, $3, NULL)
 RETURNING *;
 
 -- name: GetRefreshToken :one
-SELECT user_id, expires_at FROM refresh_tokens WHERE token = $1;
+SELECT user_id, expires_at FROM refresh_tokens WHERE token = $1 AND revoked_at IS NULL;
 
 -- name: RevokeToken :one
-UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE token = $1 RETURNING *;
\ No newline at end of file
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE token = $1 RETURNING *;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES(
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -16,10 +17,61 @@ DELETE FROM users;
 SELECT * FROM users WHERE email = $1;
 
 -- name: UpdateUser :one
-UPDATE users SET email = $1, hashed_password = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 RETURNING *;
+-- only the fields that are supplied change; a new email has to be verified
+-- again
+UPDATE users SET
+    email = COALESCE(sqlc.narg('email'), email),
+    email_verified = CASE WHEN sqlc.narg('email')::text IS NULL OR sqlc.narg('email')::text = email THEN email_verified ELSE FALSE END,
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg('id')
+RETURNING *;
 
 -- name: UpdateChirpyRed :exec
 UPDATE users SET is_chirpy_red = TRUE WHERE id = $1;
 
 -- name: GetUserById :one
-SELECT * FROM users WHERE id = $1;
\ No newline at end of file
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle::text AS handle FROM users WHERE handle = ANY(sqlc.arg('handles')::text[]);
+
+-- name: PinChirp :exec
+UPDATE users SET pinned_chirp_id = sqlc.arg('chirp_id')::uuid WHERE id = sqlc.arg('id');
+
+-- name: UnpinChirp :exec
+UPDATE users SET pinned_chirp_id = NULL
+WHERE id = sqlc.arg('id') AND pinned_chirp_id = sqlc.arg('chirp_id')::uuid;
+
+-- name: GetUserProfile :one
+-- chirp_count only counts chirps the viewer is allowed to see
+SELECT users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_media_id,
+    users.is_chirpy_red, users.pinned_chirp_id,
+    (SELECT COUNT(*) FROM chirps
+        WHERE chirps.user_id = users.id
+            AND chirps.deleted_at IS NULL
+            AND (chirps.visibility = 'public'
+                OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+                OR (chirps.visibility = 'followers' AND EXISTS (
+                    SELECT 1 FROM follows
+                    WHERE follows.follower_id = sqlc.narg('viewer_id')::uuid AND follows.followee_id = chirps.user_id)))
+    ) AS chirp_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count
+FROM users
+WHERE users.id = sqlc.arg('id');
+
+-- name: GetUserIdByHandle :one
+SELECT id FROM users WHERE handle = $1;
+
+-- name: UpdateUserProfile :one
+-- only the fields that are supplied change; set_avatar distinguishes
+-- clearing the avatar from leaving it alone
+UPDATE users SET
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_media_id = CASE WHEN sqlc.arg('set_avatar')::boolean THEN sqlc.narg('avatar_media_id')::uuid ELSE avatar_media_id END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg('id')
+RETURNING *;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN parent_chirp_id UUID NULL REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX chirps_parent_chirp_id_idx ON chirps(parent_chirp_id);
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN parent_chirp_id;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN repost_of_id UUID NULL REFERENCES chirps(id) ON DELETE CASCADE;
+
+CREATE INDEX chirps_repost_of_id_idx ON chirps(repost_of_id);
+
+-- a user can only plainly rechirp a chirp once, quotes are unrestricted
+CREATE UNIQUE INDEX chirps_plain_rechirp_idx ON chirps(user_id, repost_of_id) WHERE body = '';
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN repost_of_id;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE banned_words(
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO banned_words (word) VALUES ('kerfuffle'), ('sharbert'), ('fornax');
+
+-- +goose Down
+DROP TABLE banned_words;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT NULL UNIQUE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN handle;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE notifications(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    chirp_id UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications(user_id, created_at);
+
+-- the same actor only notifies once per chirp and type, so edits and
+-- unlike/like cycles do not spam the inbox
+CREATE UNIQUE INDEX notifications_chirp_event_idx ON notifications(user_id, actor_id, type, chirp_id) WHERE chirp_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE notifications;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- shared across instances so Last-Event-ID means the same thing everywhere
+CREATE SEQUENCE chirp_events_id_seq;
+
+-- +goose Down
+DROP SEQUENCE chirp_events_id_seq;
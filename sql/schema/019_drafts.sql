@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE drafts(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    parent_chirp_id UUID,
+    media_ids UUID[] NOT NULL DEFAULT '{}',
+    -- set for scheduled chirps, which the scheduler publishes once due
+    publish_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_chirp_id) REFERENCES chirps(id) ON DELETE SET NULL
+);
+
+CREATE INDEX drafts_user_id_idx ON drafts(user_id);
+CREATE INDEX drafts_publish_at_idx ON drafts(publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
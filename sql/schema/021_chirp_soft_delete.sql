@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a deleted plain rechirp must not block rechirping again
+DROP INDEX chirps_plain_rechirp_idx;
+CREATE UNIQUE INDEX chirps_plain_rechirp_idx ON chirps(user_id, repost_of_id) WHERE body = '' AND deleted_at IS NULL;
+
+-- +goose Down
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+DROP INDEX chirps_plain_rechirp_idx;
+CREATE UNIQUE INDEX chirps_plain_rechirp_idx ON chirps(user_id, repost_of_id) WHERE body = '';
+DROP INDEX chirps_deleted_at_idx;
+ALTER TABLE chirps DROP COLUMN deleted_at;
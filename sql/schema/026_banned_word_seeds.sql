@@ -0,0 +1,10 @@
+-- +goose Up
+-- words seeded from BANNED_WORDS_FILE, so each is only added once and stays
+-- removed if an admin deletes it
+CREATE TABLE banned_word_seeds(
+    word TEXT PRIMARY KEY,
+    seeded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE banned_word_seeds;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- follow notifications have no chirp, so the chirp index never dedupes them
+-- and unfollow/refollow cycles would spam the inbox
+DELETE FROM notifications a
+USING notifications b
+WHERE a.chirp_id IS NULL AND b.chirp_id IS NULL
+    AND a.user_id = b.user_id AND a.actor_id = b.actor_id AND a.type = b.type
+    AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX notifications_user_event_idx ON notifications(user_id, actor_id, type) WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP INDEX notifications_user_event_idx;
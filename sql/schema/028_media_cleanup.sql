@@ -0,0 +1,8 @@
+-- +goose Up
+-- the purge job looks for unattached uploads that no profile still uses
+CREATE INDEX media_files_unattached_idx ON media_files(created_at) WHERE chirp_id IS NULL;
+CREATE INDEX users_avatar_media_id_idx ON users(avatar_media_id) WHERE avatar_media_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX users_avatar_media_id_idx;
+DROP INDEX media_files_unattached_idx;
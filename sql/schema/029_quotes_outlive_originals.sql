@@ -0,0 +1,11 @@
+-- +goose Up
+-- quotes stay when the chirp they quote is purged; PurgeDeletedChirps
+-- removes plain rechirps along with it
+ALTER TABLE chirps DROP CONSTRAINT chirps_repost_of_id_fkey;
+ALTER TABLE chirps ADD CONSTRAINT chirps_repost_of_id_fkey
+    FOREIGN KEY (repost_of_id) REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP CONSTRAINT chirps_repost_of_id_fkey;
+ALTER TABLE chirps ADD CONSTRAINT chirps_repost_of_id_fkey
+    FOREIGN KEY (repost_of_id) REFERENCES chirps(id) ON DELETE CASCADE;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- a scheduled chirp that fails to publish is retried later rather than
+-- holding up every draft due after it
+ALTER TABLE drafts ADD COLUMN publish_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE drafts ADD COLUMN publish_error TEXT;
+
+-- +goose Down
+ALTER TABLE drafts DROP COLUMN publish_error;
+ALTER TABLE drafts DROP COLUMN publish_attempts;
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"david-galdamez/chirp/internal/events"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const streamHeartbeat = time.Second * 30
+
+type ChirpDeletedEvent struct {
+	ID     uuid.UUID `json:"id"`
+	UserId uuid.UUID `json:"user_id"`
+}
+
+type UserUpgradedEvent struct {
+	UserId uuid.UUID `json:"user_id"`
+}
+
+// eventPayload is the value sent to clients for an event.
+func eventPayload(event events.Event) any {
+	switch {
+	case event.Type == events.ChirpCreated && event.Chirp != nil:
+		return event.Chirp
+	case event.Type == events.UserUpgraded:
+		return UserUpgradedEvent{UserId: event.UserID}
+	}
+
+	return ChirpDeletedEvent{
+		ID:     event.ChirpID,
+		UserId: event.UserID,
+	}
+}
+
+// eventData is the JSON payload sent to clients for an event.
+func eventData(event events.Event) ([]byte, error) {
+	return json.Marshal(eventPayload(event))
+}
+
+// streamChirps pushes chirp lifecycle events as Server-Sent Events. Clients
+// resume after a disconnect by sending the Last-Event-ID header.
+func (cfg *apiConfig) streamChirps(w http.ResponseWriter, r *http.Request) {
+	authorId := uuid.NullUUID{}
+	if raw := r.URL.Query().Get("author_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "bad format from author id"}`))
+			return
+		}
+		authorId = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	var lastEventId uint64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid Last-Event-ID"}`))
+			return
+		}
+		lastEventId = parsed
+	}
+
+	rc := http.NewResponseController(w)
+
+	sub, backlog := cfg.hub.Subscribe(lastEventId)
+	defer cfg.hub.Unsubscribe(sub)
+
+	w.Header().Add("Content-Type", "text/event-stream")
+	w.Header().Add("Cache-Control", "no-cache")
+	w.Header().Add("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event events.Event) error {
+		if authorId.Valid && event.UserID != authorId.UUID {
+			return nil
+		}
+
+		data, err := eventData(event)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	for _, event := range backlog {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			// the hub closes the channel when we fall too far behind, the
+			// client reconnects with Last-Event-ID and replays the gap
+			if !ok {
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+}
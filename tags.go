@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"david-galdamez/chirp/internal/database"
+	"david-galdamez/chirp/internal/entities"
+	"david-galdamez/chirp/internal/pagination"
+	"david-galdamez/chirp/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTrendingWindow = time.Hour * 24
+	maxTrendingWindow     = time.Hour * 24 * 30
+)
+
+// saveChirpEntities replaces the stored hashtags, links and mentions of a
+// chirp with the ones found in its body and notifies mentioned users.
+// Mentions of unknown handles are skipped.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.DeleteChirpTags(ctx, chirp.ID); err != nil {
+		return err
+	}
+
+	for _, tag := range entities.Hashtags(chirp.Body) {
+		tagId, err := q.UpsertTag(ctx, tag)
+		if err != nil {
+			return err
+		}
+
+		if err := q.AddChirpTag(ctx, database.AddChirpTagParams{
+			ChirpID: chirp.ID,
+			TagID:   tagId,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := q.DeleteChirpLinks(ctx, chirp.ID); err != nil {
+		return err
+	}
+
+	for i, link := range entities.URLs(chirp.Body) {
+		if err := q.AddChirpLink(ctx, database.AddChirpLinkParams{
+			ChirpID:  chirp.ID,
+			Url:      link,
+			Position: int32(i),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return err
+	}
+
+	handles := entities.Mentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+
+	users, err := q.GetUsersByHandles(ctx, handles)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := q.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID: chirp.ID,
+			UserID:  user.ID,
+		}); err != nil {
+			return err
+		}
+
+		// mentioning someone who can't see the chirp must not reveal it
+		visible, err := canViewChirp(ctx, q, chirp, uuid.NullUUID{UUID: user.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		if !visible {
+			continue
+		}
+
+		if err := notify(ctx, q, user.ID, chirp.UserID, notificationMention, uuid.NullUUID{UUID: chirp.ID, Valid: true}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) getTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "tag not provided"}`))
+		return
+	}
+
+	viewer, err := cfg.viewerId(r)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid limit"}`))
+		return
+	}
+
+	cursorCreatedAt, cursorId, err := pagination.ParseCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid cursor"}`))
+		return
+	}
+
+	chirpsDB, err := cfg.queries.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		Tag:             tag,
+		ViewerID:        viewer,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorId,
+		PageLimit:       int32(limit + 1),
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	page := models.ChirpsPage{}
+	if len(chirpsDB) > limit {
+		chirpsDB = chirpsDB[:limit]
+		last := chirpsDB[len(chirpsDB)-1]
+		nextCursor := pagination.EncodeCursor(last.CreatedAt, last.ID)
+		page.NextCursor = &nextCursor
+	}
+
+	page.Chirps, err = cfg.toChirpModels(r.Context(), chirpsDB, viewer)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	jsonRes, err := json.Marshal(page)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
+
+func (cfg *apiConfig) getTrendingTags(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 || parsed > maxTrendingWindow {
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid window"}`))
+			return
+		}
+		window = parsed
+	}
+
+	limit, err := pagination.ParseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid limit"}`))
+		return
+	}
+
+	rows, err := cfg.queries.GetTrendingTags(r.Context(), database.GetTrendingTagsParams{
+		Since:     time.Now().UTC().Add(-window),
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	tags := []models.TrendingTag{}
+	for _, row := range rows {
+		tags = append(tags, models.TrendingTag{
+			Tag:        row.Name,
+			ChirpCount: row.ChirpCount,
+		})
+	}
+
+	jsonRes, err := json.Marshal(tags)
+	if err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Internal server error"}`))
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonRes)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"david-galdamez/chirp/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityPrivate   = "private"
+)
+
+// chirpVisibilities lists who a chirp can be shown to: everyone, the
+// author's followers, or only the author.
+var chirpVisibilities = map[string]bool{
+	visibilityPublic:    true,
+	visibilityFollowers: true,
+	visibilityPrivate:   true,
+}
+
+// canViewChirp applies the same rules as the visibility filter in the chirp
+// list queries to a single chirp. Handlers answer 404 when it fails, so
+// hidden chirps cannot be told apart from missing ones.
+func (cfg *apiConfig) canViewChirp(ctx context.Context, chirp database.Chirp, viewer uuid.NullUUID) (bool, error) {
+	return canViewChirp(ctx, cfg.queries, chirp, viewer)
+}
+
+// canViewChirp is the query-scoped form of apiConfig.canViewChirp, for use
+// inside transactions.
+func canViewChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, viewer uuid.NullUUID) (bool, error) {
+	if chirp.Visibility == visibilityPublic {
+		return true, nil
+	}
+
+	if !viewer.Valid {
+		return false, nil
+	}
+
+	if chirp.UserID == viewer.UUID {
+		return true, nil
+	}
+
+	if chirp.Visibility != visibilityFollowers {
+		return false, nil
+	}
+
+	return q.IsFollowing(ctx, database.IsFollowingParams{
+		FollowerID: viewer.UUID,
+		FolloweeID: chirp.UserID,
+	})
+}
+
+// getVisibleChirp fetches a chirp the viewer is allowed to see. Hidden
+// chirps are reported as sql.ErrNoRows, like missing ones.
+func (cfg *apiConfig) getVisibleChirp(ctx context.Context, id uuid.UUID, viewer uuid.NullUUID) (database.Chirp, error) {
+	chirp, err := cfg.queries.GetChirp(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	visible, err := cfg.canViewChirp(ctx, chirp, viewer)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if !visible {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	return chirp, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"david-galdamez/chirp/internal/auth"
+	"david-galdamez/chirp/internal/events"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsPingInterval     = time.Second * 30
+	wsPongWait         = time.Second * 60
+	wsWriteWait        = time.Second * 10
+	wsMaxMessageSize   = 4096
+	wsMaxSubscriptions = 50
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+type WSRequest struct {
+	Action  string      `json:"action"`
+	Authors []uuid.UUID `json:"authors"`
+	Tags    []string    `json:"tags"`
+}
+
+type WSMessage struct {
+	Type    string `json:"type"`
+	EventId uint64 `json:"event_id,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// wsSubscriptions is the set of authors and tags a connection listens to.
+type wsSubscriptions struct {
+	mu      sync.Mutex
+	authors map[uuid.UUID]bool
+	tags    map[string]bool
+}
+
+func (s *wsSubscriptions) subscribe(authors []uuid.UUID, tags []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, author := range authors {
+		if !s.authors[author] {
+			added++
+		}
+	}
+	for _, tag := range tags {
+		if !s.tags[strings.ToLower(tag)] {
+			added++
+		}
+	}
+
+	if len(s.authors)+len(s.tags)+added > wsMaxSubscriptions {
+		return false
+	}
+
+	for _, author := range authors {
+		s.authors[author] = true
+	}
+	for _, tag := range tags {
+		s.tags[strings.ToLower(tag)] = true
+	}
+
+	return true
+}
+
+func (s *wsSubscriptions) unsubscribe(authors []uuid.UUID, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, author := range authors {
+		delete(s.authors, author)
+	}
+	for _, tag := range tags {
+		delete(s.tags, strings.ToLower(tag))
+	}
+}
+
+// matches reports whether an event is for a subscribed author or, for new
+// chirps, carries a subscribed tag. Deletions and upgrades only carry the
+// author.
+func (s *wsSubscriptions) matches(event events.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.authors[event.UserID] {
+		return true
+	}
+
+	if event.Chirp != nil {
+		for _, tag := range event.Chirp.Tags {
+			if s.tags[tag] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// chirpsWebSocket serves live chirp events over a WebSocket. Browsers cannot
+// set headers on the handshake, so the JWT may also be sent as ?token=.
+func (cfg *apiConfig) chirpsWebSocket(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		token = r.URL.Query().Get("token")
+	}
+
+	if _, err := auth.ValidateJWT(token, cfg.secretKey); err != nil {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "unauthorized"}`))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	subs := &wsSubscriptions{
+		authors: map[uuid.UUID]bool{},
+		tags:    map[string]bool{},
+	}
+
+	sub, _ := cfg.hub.Subscribe(0)
+	defer cfg.hub.Unsubscribe(sub)
+
+	replies := make(chan WSMessage, 8)
+	done := make(chan struct{})
+	closed := make(chan struct{})
+	defer close(closed)
+
+	reply := func(message WSMessage) bool {
+		select {
+		case replies <- message:
+			return true
+		case <-closed:
+			return false
+		}
+	}
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// only this goroutine reads and only the loop below writes, as gorilla
+	// allows one concurrent reader and one concurrent writer
+	go func() {
+		defer close(done)
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			request := WSRequest{}
+			if err := json.Unmarshal(data, &request); err != nil {
+				if !reply(WSMessage{Type: "error", Error: "malformed message"}) {
+					return
+				}
+				continue
+			}
+
+			message := WSMessage{}
+			switch request.Action {
+			case "subscribe":
+				if subs.subscribe(request.Authors, request.Tags) {
+					message = WSMessage{Type: "subscribed"}
+				} else {
+					message = WSMessage{Type: "error", Error: "subscription limit reached"}
+				}
+			case "unsubscribe":
+				subs.unsubscribe(request.Authors, request.Tags)
+				message = WSMessage{Type: "unsubscribed"}
+			case "ping":
+				message = WSMessage{Type: "pong"}
+			default:
+				message = WSMessage{Type: "error", Error: "unknown action"}
+			}
+
+			if !reply(message) {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	write := func(message WSMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(message)
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case message := <-replies:
+			if err := write(message); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"),
+					time.Now().Add(wsWriteWait),
+				)
+				return
+			}
+
+			if !subs.matches(event) {
+				continue
+			}
+
+			if err := write(WSMessage{Type: event.Type, EventId: event.ID, Data: eventPayload(event)}); err != nil {
+				return
+			}
+		}
+	}
+}